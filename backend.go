@@ -2,7 +2,9 @@ package objectbind
 
 import (
 	"context"
+	"io"
 	"net/url"
+	"time"
 
 	"github.com/ti/objectbind/file"
 )
@@ -14,7 +16,60 @@ type Backend interface {
 	Watch(ctx context.Context, paths []string, onChange func(map[string][]byte)) error
 }
 
-// NewBackend new backend
+// VersionedBackend the optional interface of the backend which tracks the revisions of the paths,
+// the binder saves the data only when the revisions are not changed since it loaded them
+type VersionedBackend interface {
+	Backend
+	// LoadRevision load the data and the revision of every loaded path
+	LoadRevision(ctx context.Context, path string) (data map[string][]byte, revisions map[string]string, err error)
+	// SaveRevision save the data when the revision of path is still revision, the empty revision means the
+	// path does not exist and the empty data deletes the path. It returns the new revision of path, if the revision
+	// is changed the returned error should have a Conflicts() []string method
+	SaveRevision(ctx context.Context, path string, data []byte, revision string) (newRevision string, err error)
+}
+
+// TxBackend the optional interface of the backend which saves multiple paths all or nothing
+type TxBackend interface {
+	Backend
+	// SaveTx save the data of all the paths in a transaction, the empty data deletes the path. If revisions is not nil,
+	// the paths are saved only when all of their revisions are not changed, the same as VersionedBackend.SaveRevision.
+	// It returns the new revisions of the saved paths
+	SaveTx(ctx context.Context, data map[string][]byte, revisions map[string]string) (newRevisions map[string]string, err error)
+}
+
+// StatusWatcher the optional interface of the backend which reports the status of its watches, onStatus is called
+// with connected false and the error when the watch is broken, and with connected true when it is restarted or as
+// the heartbeat of the watch. The binder reloads all the files after the watch is restarted because the changes may be lost
+type StatusWatcher interface {
+	Backend
+	WatchStatus(ctx context.Context, paths []string, onChange func(map[string][]byte), onStatus func(connected bool, err error)) error
+}
+
+// HeartbeatWatcher the optional interface of the StatusWatcher which calls onStatus with connected true as the heartbeat
+// of its watches, the connected watches of the other backends count as fresh for WithMaxStaleness
+type HeartbeatWatcher interface {
+	StatusWatcher
+	// HeartbeatInterval the max interval between the heartbeats of a connected watch
+	HeartbeatInterval() time.Duration
+}
+
+// Closer the optional interface of the backend, the binder closes the backend it created when it is closed,
+// backends implementing io.Closer are closed as well
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+func closeBackend(ctx context.Context, backend Backend) error {
+	switch c := backend.(type) {
+	case Closer:
+		return c.Close(ctx)
+	case io.Closer:
+		return c.Close()
+	}
+	return nil
+}
+
+// NewBackend new backend, the logger of the binder can be got by LoggerFromContext(ctx)
 type NewBackend func(ctx context.Context, uri *url.URL) (Backend, error)
 
 var backends = make(map[string]NewBackend)
@@ -31,6 +86,6 @@ func SetBackend(scheme string, backend NewBackend) {
 // init load etcd and file to default backend
 func init() {
 	SetBackend(schemeFile, func(ctx context.Context, uri *url.URL) (Backend, error) {
-		return file.New(ctx, uri)
+		return file.New(ctx, uri, file.WithLogger(LoggerFromContext(ctx)))
 	})
 }
@@ -0,0 +1,70 @@
+package objectbind
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// binaryCodec the codec of the compact binary formats such as CBOR and MessagePack, the values are encoded in their
+// json form without the null members of the objects, so the etcd values stay small
+type binaryCodec struct {
+	name      string
+	marshal   func(v interface{}) ([]byte, error)
+	unmarshal func(data []byte, v interface{}) error
+}
+
+var (
+	cborEnc, _ = cbor.CoreDetEncOptions().EncMode()
+	cborDec, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}(nil))}.DecMode()
+)
+
+func newCBORCodec() *binaryCodec {
+	return &binaryCodec{name: "cbor", marshal: cborEnc.Marshal, unmarshal: cborDec.Unmarshal}
+}
+
+func newMsgpackCodec() *binaryCodec {
+	return &binaryCodec{name: "msgpack", marshal: msgpack.Marshal, unmarshal: msgpack.Unmarshal}
+}
+
+func (c *binaryCodec) String() string {
+	return c.name
+}
+
+func (c *binaryCodec) Unmarshal(data []byte, v interface{}) error {
+	var value interface{}
+	if err := c.unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+	return decodeJSONValue(value, v)
+}
+
+func (c *binaryCodec) Marshal(v interface{}) ([]byte, error) {
+	value, err := jsonForm(v, binaryValue)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return c.marshal(value)
+}
+
+// binaryValue convert the json leaf to the binary value, the json numbers are converted to the integers, the unsigned
+// integers greater than int64 or the floats
+func binaryValue(v interface{}) (interface{}, error) {
+	x, ok := v.(json.Number)
+	if !ok {
+		return v, nil
+	}
+	if i, err := x.Int64(); err == nil {
+		return i, nil
+	}
+	if u, err := strconv.ParseUint(x.String(), 10, 64); err == nil {
+		return u, nil
+	}
+	return x.Float64()
+}
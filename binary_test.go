@@ -0,0 +1,44 @@
+package objectbind
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBinaryCodecs(t *testing.T) {
+	for _, codec := range []*binaryCodec{newCBORCodec(), newMsgpackCodec()} {
+		codec := codec
+		t.Run(codec.String(), func(t *testing.T) {
+			testCodecRoundTrip(t, codec, append(codecRoundTripTests[:len(codecRoundTripTests):len(codecRoundTripTests)],
+				codecRoundTripTest{name: "max unsigned", conf: codecTestConf{Size: math.MaxUint64}}))
+			empty, err := codec.Marshal(map[string]interface{}{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			testCodecUnmarshal(t, codec, []codecUnmarshalTest{
+				{name: "empty object", data: string(empty)},
+				{name: "empty data", data: "", err: "EOF"},
+				{name: "truncated", data: "\x82", err: "EOF"},
+			})
+		})
+	}
+}
+
+func TestBinaryCodecOmitsNull(t *testing.T) {
+	for _, codec := range []*binaryCodec{newCBORCodec(), newMsgpackCodec()} {
+		data, err := codec.Marshal(map[string]interface{}{"a": 1, "b": nil, "c": []interface{}{nil}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]interface{}
+		if err = codec.unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := got["b"]; ok || len(got) != 2 {
+			t.Fatalf("%s keeps the null member, got %v", codec, got)
+		}
+		if data, err = codec.Marshal(nil); err != nil || data != nil {
+			t.Fatalf("%s marshal null to %x, %v, want nothing", codec, data, err)
+		}
+	}
+}
@@ -6,21 +6,29 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
-	"os"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Binder the binder instance
 type Binder struct {
-	backend  Backend
-	codec    Codec
-	locker   sync.Locker
-	instance interface{}
-	triggers []*trigger
-	tagName  string
+	backend     Backend
+	codec       Codec
+	customCodec bool
+	locker      sync.Locker
+	instance    interface{}
+	triggers    []*trigger
+	tagName     string
+	logger      Logger
+	scheme      string
+
+	// deliveries the pending calls of the triggers, they are called by deliver without holding the locker
+	deliveries []*delivery
+	delivering bool
+	deliverMu  sync.RWMutex
 
 	// files
 	root         string
@@ -29,15 +37,58 @@ type Binder struct {
 	currentFiles map[string]*mapData
 
 	preInstance interface{}
+	// revisions the revisions of the files in the versioned backend
+	revisions map[string]string
+
+	// debounce
+	debounce      time.Duration
+	pendingMu     sync.Mutex
+	pending       map[string]*mapData
+	debounceTimer *time.Timer
+	flushMu       sync.Mutex
+
+	// validation
+	validator func(candidate interface{}) error
+	validates bool
+	onError   func(err error)
+
+	// snapshot mode
+	snapshot bool
+	current  atomic.Value
+
+	// events
+	events       chan Event
+	eventMu      sync.RWMutex
+	eventsClosed bool
+
+	// status
+	statusMu sync.Mutex
+	status   binderStatus
 
 	withExtension bool
 	extension     string
 	lenExtension  int
+
+	// lifecycle
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	closed       bool
+	closeBackend bool
 }
 
 type trigger struct {
 	filed    string
-	callback func(value, preValue interface{})
+	callback func(c Change)
+	onClose  func()
+	removed  int32
+	// wildcard the field has wildcards, the callback is called per changed entry
+	wildcard bool
+}
+
+// Validator the bound object implementing Validator is validated before it is changed and saved
+type Validator interface {
+	Validate() error
 }
 
 // Bind bind target to uri
@@ -58,15 +109,20 @@ func Bind(ctx context.Context, target interface{}, uri string, opts ...Option) (
 		u.Scheme = schemeFile
 	}
 
+	if opt.logger == nil {
+		opt.logger = defaultLogger
+	}
+	var closeBackend bool
 	if opt.backend == nil {
 		backend, ok := backends[u.Scheme]
 		if !ok {
 			return nil, fmt.Errorf("%s for bind is not supported", u.Scheme)
 		}
-		opt.backend, err = backend(ctx, u)
+		opt.backend, err = backend(contextWithLogger(ctx, opt.logger), u)
 		if err != nil {
 			return nil, err
 		}
+		closeBackend = true
 	}
 	if opt.ttl == 0 {
 		if t := u.Query().Get("ttl"); t != "" {
@@ -81,11 +137,14 @@ func Bind(ctx context.Context, target interface{}, uri string, opts ...Option) (
 		}
 	}
 	ext := filepath.Ext(u.Path)
-	if opt.codec == nil {
-		if c, ok := defaultCodes[ext]; ok {
-			opt.codec = c
-		} else {
-			opt.codec = defaultCodes[".json"]
+	customCodec := opt.codec != nil
+	if !customCodec {
+		// the json codec is used when the extension means nothing or has no registered codec
+		var ok bool
+		if ext == "" || opt.withoutExtension {
+			opt.codec, _ = LookupCodec(".json")
+		} else if opt.codec, ok = LookupCodec(ext); !ok {
+			opt.codec, _ = LookupCodec(".json")
 		}
 	}
 	root := u.Path
@@ -107,13 +166,35 @@ func Bind(ctx context.Context, target interface{}, uri string, opts ...Option) (
 		backend:       opt.backend,
 		root:          root,
 		codec:         opt.codec,
+		customCodec:   customCodec,
 		withExtension: !opt.withoutExtension,
 		extension:     ext,
 		lenExtension:  len(ext),
 		tagName:       opt.tagName,
+		logger:        opt.logger,
+		scheme:        u.Scheme,
+		closeBackend:  closeBackend,
+		snapshot:      opt.snapshot,
+		debounce:      opt.debounce,
+		validator:     opt.validator,
+		onError:       opt.onError,
+		events:        make(chan Event, eventBufferSize),
+		status:        binderStatus{maxStaleness: opt.maxStaleness},
 	}
+	_, binder.status.heartbeats = opt.backend.(HeartbeatWatcher)
+	_, binder.validates = target.(Validator)
+	binder.ctx, binder.cancel = context.WithCancel(ctx)
 	err = binder.init(ctx, opt)
-	return binder, err
+	if err != nil {
+		_ = binder.Close(context.Background())
+		return binder, err
+	}
+	go func() {
+		// the binder is closed when the ctx of Bind is done
+		<-binder.ctx.Done()
+		_ = binder.Close(context.Background())
+	}()
+	return binder, nil
 }
 
 // BindField bind field
@@ -136,76 +217,322 @@ func (b *Binder) init(ctx context.Context, opt *Options) (err error) {
 	}
 	if len(files) == 0 {
 		err = b.saveCurrentDataWithoutCompare(ctx)
-	} else {
+		if _, ok := conflictKeys(err); ok {
+			// the data is saved by other binders, load it
+			files, err = b.loadFiles(ctx)
+			if err != nil {
+				return fmt.Errorf("load all files error for %s", err)
+			}
+		}
+	}
+	if len(files) > 0 {
 		err = unmarshal(b.root, files, b.instance, b.tagName)
 		b.save2CurrentFiles(files)
 	}
 	if err != nil {
 		return
 	}
+	if b.snapshot {
+		b.current.Store(clone(b.instance, true))
+	}
+	b.preInstance = clone(b.value(), false)
+	b.emit(EventLoaded, b.filePaths(b.currentKeys()), nil)
 	if opt.ttl >= time.Second {
-		go func() {
-			for {
-				// Delay after each request
-				<-time.After(opt.ttl)
-				// Attempt to reload the config
-				err = b.ForceLoad(context.Background())
-				if err != nil {
-					warnLog("objectbind", fmt.Sprintf("forceLoad error %v", err))
-				}
-			}
-		}()
+		b.wg.Add(1)
+		go b.reloadLoop(opt.ttl)
 	}
-	b.preInstance = clone(b.instance, false)
 	if !opt.withoutWatch {
-		err = b.watch(ctx)
+		if err = b.watch(b.ctx); err == nil {
+			b.statusMu.Lock()
+			b.status.watching = true
+			b.statusMu.Unlock()
+		}
 	}
 	return
 }
 
-// BindField bind field
-func (b *Binder) BindField(field string, onValue func(value, preValue interface{})) {
+// reloadLoop reload the data in a ttl loop until the binder is closed
+func (b *Binder) reloadLoop(ttl time.Duration) {
+	defer b.wg.Done()
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+			// Attempt to reload the config
+			if err := b.ForceLoad(b.ctx); err != nil && b.ctx.Err() == nil {
+				b.reportError("reload", b.root, err)
+			}
+		}
+	}
+}
+
+// Close stop the watchers and the reload loop, close the backend if it is created by Bind,
+// no callback or error handler will be fired after Close returns, the backend is closed even if ctx is done
+func (b *Binder) Close(ctx context.Context) error {
 	b.locker.Lock()
-	defer b.locker.Unlock()
-	v, err := getFieldValue(b.instance, field, true, false)
-	if err != nil {
-		panic(fmt.Errorf("bind field %s error for %s", field, err))
+	if b.closed {
+		b.locker.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.locker.Unlock()
+	b.cancel()
+	b.pendingMu.Lock()
+	if b.debounceTimer != nil {
+		b.debounceTimer.Stop()
 	}
-	onValue(v, v)
-	tg := &trigger{
-		filed:    field,
-		callback: onValue,
+	b.pending = nil
+	b.pendingMu.Unlock()
+	b.closeEvents()
+	// wait for the running callbacks
+	b.deliverMu.Lock()
+	b.deliverMu.Unlock()
+	b.locker.Lock()
+	triggers := b.triggers
+	b.triggers = nil
+	b.locker.Unlock()
+	for _, t := range triggers {
+		if t.onClose != nil {
+			t.onClose()
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// the backend is closed anyway, so the watchers of the background loops are stopped
+		err = ctx.Err()
+	}
+	if !b.closeBackend {
+		return err
+	}
+	if closeErr := closeBackend(ctx, b.backend); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// BindField bind field, it panics if the field is not valid, use OnChange to get the error and unsubscribe
+func (b *Binder) BindField(field string, onValue func(value, preValue interface{})) {
+	if _, err := b.OnChange(field, onValue); err != nil {
+		panic(err)
 	}
-	b.triggers = append(b.triggers, tg)
 }
 
 // ForceLoad force load form backend
 func (b *Binder) ForceLoad(ctx context.Context) error {
+	defer b.deliver()
+	b.locker.Lock()
+	defer b.locker.Unlock()
 	files, err := b.loadFiles(ctx)
 	if err != nil {
-		return fmt.Errorf("load all files error for %s", err)
+		err = fmt.Errorf("load all files error for %w", err)
+		typ, paths := b.loadEvent(b.root, err)
+		b.emit(typ, paths, err)
+		return err
 	}
 	if len(files) == 0 {
+		b.emit(EventBackendError, []string{b.getFileName(b.root)}, ErrNoFiles)
 		return ErrNoFiles
 	}
-	err = unmarshal(b.root, files, b.instance, b.tagName)
+	err = b.decode(files, b.dirPaths())
 	if err != nil {
+		b.emit(decodeEventType(err), b.filePaths(fileKeys(files)), err)
 		return err
 	}
-	b.notifyChanges(ctx)
+	b.save2CurrentFiles(files)
+	patch := b.notifyChanges(ctx)
+	b.emitPatch(EventLoaded, b.filePaths(fileKeys(files)), patch)
 	return nil
 }
 
 // Save save the data
 func (b *Binder) Save(ctx context.Context) error {
-	memoryData, err := marshal(b.root, b.instance, b.tagName)
+	defer b.deliver()
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if err := b.validate(b.value()); err != nil {
+		return err
+	}
+	memoryData, err := marshal(b.root, b.value(), b.tagName)
+	if err != nil {
+		return err
+	}
+	if err = b.saveChanges(ctx, memoryData); err != nil {
+		return err
+	}
+	b.save2CurrentFiles(memoryData)
+	b.notifyChanges(ctx)
+	return nil
+}
+
+// Update run fn on a copy of the bound object under the binder lock, then save the changed files and apply the copy,
+// nothing is applied when fn or the saving returns an error
+func (b *Binder) Update(ctx context.Context, fn func(target interface{}) error) error {
+	defer b.deliver()
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	target, err := deepCopy(b.value())
+	if err != nil {
+		return err
+	}
+	if err = fn(target); err != nil {
+		return err
+	}
+	if err = b.validate(target); err != nil {
+		return err
+	}
+	memoryData, err := marshal(b.root, target, b.tagName)
+	if err != nil {
+		return err
+	}
+	if err = b.saveChanges(ctx, memoryData); err != nil {
+		return err
+	}
+	b.apply(target)
+	b.save2CurrentFiles(memoryData)
+	b.notifyChanges(ctx)
+	return nil
+}
+
+// Load return the bound object, in snapshot mode it is the latest published snapshot,
+// which is never changed by the binder and safe for concurrent reading
+func (b *Binder) Load() interface{} {
+	return b.value()
+}
+
+func (b *Binder) value() interface{} {
+	if b.snapshot {
+		return b.current.Load()
+	}
+	return b.instance
+}
+
+// decode unmarshal the files to the bound object, the fields of dirs are reset before decoding so that the removed
+// files are removed from them. The files are decoded to a copy of the current object to validate it first, in snapshot
+// mode the copy is published when it is valid
+func (b *Binder) decode(files []*mapData, dirs []string) error {
+	if !b.snapshot && !b.validating() {
+		return b.decodeTo(b.instance, files, dirs)
+	}
+	candidate, err := deepCopy(b.value())
 	if err != nil {
 		return err
 	}
+	if err = b.decodeTo(candidate, files, dirs); err != nil {
+		return err
+	}
+	if err = b.validate(candidate); err != nil {
+		return err
+	}
+	if b.snapshot {
+		b.current.Store(candidate)
+		return nil
+	}
+	return b.decodeTo(b.instance, files, dirs)
+}
+
+func (b *Binder) decodeTo(target interface{}, files []*mapData, dirs []string) error {
+	b.resetDirs(target, dirs)
+	if len(files) == 0 {
+		return nil
+	}
+	return unmarshal(b.root, files, target, b.tagName)
+}
+
+func (b *Binder) validating() bool {
+	return b.validates || b.validator != nil
+}
+
+// validate check the candidate by its Validate method and the validator of the options
+func (b *Binder) validate(candidate interface{}) error {
+	if v, ok := candidate.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+	if b.validator != nil {
+		if err := b.validator(candidate); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+	return nil
+}
+
+// reportError report the errors of the background loading to the logger and the error handler, the handler is called
+// by deliver in order with the callbacks, so it is not called after Close returns
+func (b *Binder) reportError(op, path string, err error) {
+	b.logger.Warn("objectbind: "+op+" error", "op", op, "path", path, "backend", b.scheme, "error", err)
+	if b.onError == nil {
+		return
+	}
+	b.locker.Lock()
+	if b.closed {
+		b.locker.Unlock()
+		return
+	}
+	b.deliveries = append(b.deliveries, &delivery{err: err})
+	b.locker.Unlock()
+	b.deliver()
+}
+
+// resetDirs set the fields bound to dirs to zero
+func (b *Binder) resetDirs(target interface{}, dirs []string) {
+	rv := reflect.ValueOf(target).Elem()
+	for _, dir := range dirs {
+		f, ok := b.fields[dir]
+		if !ok || !strings.HasSuffix(dir, "/") {
+			continue
+		}
+		v := rv
+		if f.Field != "" {
+			v = rv.FieldByName(f.Field)
+		}
+		if v.CanSet() {
+			v.Set(reflect.Zero(v.Type()))
+		}
+	}
+}
+
+// dirPaths the paths of the dirs in fields
+func (b *Binder) dirPaths() []string {
+	var dirs []string
+	for k := range b.fields {
+		if strings.HasSuffix(k, "/") {
+			dirs = append(dirs, k)
+		}
+	}
+	return dirs
+}
+
+// apply replace the bound object with the value
+func (b *Binder) apply(value interface{}) {
+	if b.snapshot {
+		b.current.Store(value)
+		return
+	}
+	reflect.ValueOf(b.instance).Elem().Set(reflect.ValueOf(value).Elem())
+}
+
+// saveChanges save the data which is different from currentFiles
+func (b *Binder) saveChanges(ctx context.Context, memoryData []*mapData) error {
 	// compare
 	var todoSave []*mapData
 	for _, memoryItem := range memoryData {
 		currentItem, ok := b.currentFiles[memoryItem.Key]
+		if ok {
+			// keep the extensions and the states of the files
+			memoryItem.Ext = currentItem.Ext
+			memoryItem.state = currentItem.state
+		}
 		if !ok || memoryItem.Value != currentItem.Value {
 			todoSave = append(todoSave, memoryItem)
 		}
@@ -224,19 +551,12 @@ func (b *Binder) Save(ctx context.Context) error {
 			})
 		}
 	}
+	var files []*mapData
 	for _, v := range todoSave {
 		if v.Value == "null" || strings.HasSuffix(v.Key, "/") {
 			continue
 		}
-		if err := b.saveJSONFile(ctx, v.Key, []byte(v.Value)); err != nil {
-			return err
-		}
+		files = append(files, v)
 	}
-	return nil
-}
-
-
-func warnLog(action, msg string)  {
-	_, _ = fmt.Fprintf(os.Stdout, `{"level":"warn","time":"%s","msg":"%s","action":"%s"}`+"\n",
-		time.Now().UTC().Format(time.RFC3339), msg, action)
+	return b.saveFiles(ctx, files)
 }
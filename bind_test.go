@@ -0,0 +1,221 @@
+package objectbind
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ti/objectbind/file"
+)
+
+type bindTestServer struct {
+	Host  string `json:"host"`
+	Token string `json:"-"`
+}
+
+type bindTestConf struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Server *bindTestServer   `json:"server"`
+	Secret string            `json:"-"`
+	cache  map[string]int
+}
+
+// writeTestFiles write the files to dir, the parent dirs of the files are created
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// bindTestDir bind target to config.json of dir, the binder is closed when the test finishes
+func bindTestDir(t *testing.T, target interface{}, dir string, opts ...Option) *Binder {
+	return bindTestFile(t, target, filepath.Join(dir, "config.json"), opts...)
+}
+
+// bindTestFile bind target to the file of path, the binder is closed when the test finishes
+func bindTestFile(t *testing.T, target interface{}, path string, opts ...Option) *Binder {
+	b, err := Bind(context.Background(), target, path, append([]Option{WithLogger(file.NewJSONLogger(io.Discard))}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = b.Close(context.Background())
+	})
+	return b
+}
+
+func TestUpdateKeepsHiddenFields(t *testing.T) {
+	conf := &bindTestConf{}
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a","labels":{"k":"v"},"server":{"host":"h"}}`})
+	b := bindTestDir(t, conf, dir)
+	conf.Secret, conf.Server.Token, conf.cache = "secret", "token", map[string]int{"a": 1}
+	err := b.Update(context.Background(), func(target interface{}) error {
+		c := target.(*bindTestConf)
+		c.Name = "b"
+		c.Labels["k2"] = "v2"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "b" || conf.Labels["k2"] != "v2" {
+		t.Fatalf("the update is not applied, got %+v", conf)
+	}
+	if conf.Secret != "secret" || conf.Server.Token != "token" || conf.cache["a"] != 1 {
+		t.Fatalf("the fields json can not see are lost, got %+v, server %+v", conf, conf.Server)
+	}
+}
+
+func TestUpdateFailureKeepsObject(t *testing.T) {
+	conf := &bindTestConf{}
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a","labels":{"k":"v"},"server":{"host":"h"}}`})
+	b := bindTestDir(t, conf, dir)
+	server := conf.Server
+	errUpdate := errors.New("update failed")
+	err := b.Update(context.Background(), func(target interface{}) error {
+		c := target.(*bindTestConf)
+		c.Labels["k"] = "changed"
+		c.Server.Host = "changed"
+		return errUpdate
+	})
+	if err != errUpdate {
+		t.Fatalf("got error %v, want %v", err, errUpdate)
+	}
+	if conf.Labels["k"] != "v" || conf.Server != server || server.Host != "h" {
+		t.Fatalf("the failed update changes the object, got %+v, server %+v", conf, server)
+	}
+}
+
+func TestErrorHandlerNotCalledAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a"}`})
+	var mu sync.Mutex
+	var errs []error
+	started, release := make(chan struct{}), make(chan struct{})
+	reject := WithValidator(func(candidate interface{}) error {
+		if candidate.(*bindTestConf).Name == "bad" {
+			return errors.New("bad name")
+		}
+		return nil
+	})
+	b := bindTestDir(t, &bindTestConf{}, dir, reject, WithErrorHandler(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		n := len(errs)
+		mu.Unlock()
+		if n == 1 {
+			close(started)
+			<-release
+		}
+	}))
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"bad"}`})
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the error handler is not called")
+	}
+	closed := make(chan error)
+	go func() {
+		closed <- b.Close(context.Background())
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returns before the running error handler")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	b.reportError("test", "config", errors.New("after close"))
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 {
+		t.Fatalf("the error handler is called after Close, got %v", errs)
+	}
+}
+
+// closeRecordBackend record that the backend is closed
+type closeRecordBackend struct {
+	Backend
+	closed int32
+}
+
+func (c *closeRecordBackend) Close(ctx context.Context) error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+func TestCloseBackendAfterTimeout(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a"}`})
+	backend, err := file.New(context.Background(), &url.URL{Scheme: "file", Path: filepath.Join(dir, "config.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &closeRecordBackend{Backend: backend}
+	b := bindTestDir(t, &bindTestConf{}, dir, WithBackend(recorder), WithoutWatch(true))
+	// as the backend created by Bind, with a background loop which does not stop in time
+	b.closeBackend = true
+	b.wg.Add(1)
+	defer b.wg.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = b.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if atomic.LoadInt32(&recorder.closed) != 1 {
+		t.Fatal("the backend is not closed")
+	}
+}
+
+type bindTestYAMLConf struct {
+	Name   string `json:"name" yaml:"name"`
+	Ports  []int  `json:"ports" yaml:"ports"`
+	Backup []int  `json:"backup" yaml:"backup"`
+}
+
+func TestUpdateKeepsYAMLComments(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.yaml": `# the service
+name: a # the name of the service
+ports: &ports [80, 443]
+backup: *ports
+`})
+	path := filepath.Join(dir, "config.yaml")
+	b := bindTestFile(t, &bindTestYAMLConf{}, path, WithoutWatch(true))
+	err := b.Update(context.Background(), func(target interface{}) error {
+		target.(*bindTestYAMLConf).Name = "b"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# the service\n", "name: b # the name of the service\n", "&ports [80, 443]", "backup: *ports"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("got the saved file\n%s\nwant it to contain %q", data, want)
+		}
+	}
+}
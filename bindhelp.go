@@ -2,6 +2,8 @@ package objectbind
 
 import (
 	"context"
+	"fmt"
+	"sort"
 )
 
 func (b *Binder) loadFiles(ctx context.Context) ([]*mapData, error) {
@@ -23,12 +25,57 @@ func (b *Binder) saveCurrentDataWithoutCompare(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	for _, v := range data {
-		if err := b.saveJSONFile(ctx, v.Key, []byte(v.Value)); err != nil {
+	if err = b.saveFiles(ctx, data); err != nil {
+		return err
+	}
+	b.save2CurrentFiles(data)
+	return nil
+}
+
+// saveFiles save the files to the backend, the empty value deletes the file, TxBackend saves them all or nothing.
+// The states of the files are replaced by the saved ones only when all the files are saved
+func (b *Binder) saveFiles(ctx context.Context, files []*mapData) error {
+	data := make(map[string][]byte, len(files))
+	states := make(map[*mapData]interface{}, len(files))
+	var paths []string
+	for _, v := range files {
+		path := b.getFileName(v.Key)
+		paths = append(paths, path)
+		if v.Value == "" {
+			data[path] = nil
+			continue
+		}
+		fileData, state, err := b.json2Codec(v.Key, path, []byte(v.Value), v.state)
+		if err != nil {
+			return fmt.Errorf("objectbind.JSON2Codec %s error for %s", v.Key, err)
+		}
+		data[path] = fileData
+		states[v] = state
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	if err := b.saveData(ctx, paths, data); err != nil {
+		return err
+	}
+	for v, state := range states {
+		v.state = state
+	}
+	return nil
+}
+
+func (b *Binder) saveData(ctx context.Context, paths []string, data map[string][]byte) error {
+	if tx, ok := b.backend.(TxBackend); ok {
+		return b.saveTx(ctx, tx, data)
+	}
+	if err := b.checkRevisions(ctx, paths); err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := b.saveBackend(ctx, path, data[path]); err != nil {
 			return err
 		}
 	}
-	b.save2CurrentFiles(data)
 	return nil
 }
 
@@ -37,4 +84,15 @@ func (b *Binder) save2CurrentFiles(files []*mapData) {
 	for _, v := range files {
 		b.currentFiles[v.Key] = v
 	}
+	b.updateRevisions()
+}
+
+// currentKeys get the sorted keys of the current files
+func (b *Binder) currentKeys() []string {
+	keys := make([]string, 0, len(b.currentFiles))
+	for k := range b.currentFiles {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
@@ -8,6 +8,7 @@ import (
 	"gopkg.in/yaml.v3"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // Codec the codes interface for you can custom your codec
@@ -17,14 +18,72 @@ type Codec interface {
 	Unmarshal(data []byte, v interface{}) error
 }
 
-var defaultCodes = map[string]Codec{
-	".json": &jsonCodec{},
-	".yaml": &yamlCodec{},
+// FileCodec the optional interface of the codec which keeps the state of every file, such as the comments and
+// the order of the keys, so that they are kept when the file is saved
+type FileCodec interface {
+	Codec
+	// UnmarshalFile unmarshal data like Unmarshal and return the state of the file
+	UnmarshalFile(data []byte, v interface{}) (state interface{}, err error)
+	// MarshalFile marshal v like Marshal into the state of the file returned by UnmarshalFile, the state must not
+	// be changed because the saving may fail
+	MarshalFile(v interface{}, state interface{}) ([]byte, error)
 }
 
-func (b *Binder) json2Codec(path string, src []byte) ([]byte, error) {
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		".json":       &jsonCodec{},
+		".yaml":       &yamlCodec{},
+		".toml":       &tomlCodec{},
+		".jsonc":      &jsoncCodec{name: "jsonc"},
+		".json5":      &jsoncCodec{name: "json5"},
+		".env":        newEnvCodec(),
+		".properties": newPropertiesCodec(),
+		".ini":        newINICodec(),
+		".cbor":       newCBORCodec(),
+		".msgpack":    newMsgpackCodec(),
+	}
+)
+
+// RegisterCodec register the codec of the file extension such as ".toml", the registered codec of ext is replaced.
+// Bind chooses the codec of the uri and the files of the bound dirs by their extensions
+func RegisterCodec(ext string, c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[normalizeExt(ext)] = c
+}
+
+// LookupCodec get the registered codec of the file extension
+func LookupCodec(ext string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[normalizeExt(ext)]
+	return c, ok
+}
+
+func normalizeExt(ext string) string {
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return strings.ToLower(ext)
+}
+
+// fileCodec get the codec of the file, the registered codec of its extension is used unless the codec is set by WithCodec
+func (b *Binder) fileCodec(filename string) Codec {
+	if b.customCodec {
+		return b.codec
+	}
+	if c, ok := LookupCodec(filepath.Ext(filename)); ok {
+		return c
+	}
+	return b.codec
+}
+
+// json2Codec encode the json data of the file by its codec, the state is kept by the FileCodec from the loaded file.
+// It returns the state of the encoded file for the next saving, the state passed in is not changed
+func (b *Binder) json2Codec(path, filename string, src []byte, state interface{}) ([]byte, interface{}, error) {
 	if b.codec == nil {
-		return src, nil
+		return src, nil, nil
 	}
 	dir, _ := filepath.Split(path)
 	field, ok := b.fields[dir]
@@ -36,24 +95,47 @@ func (b *Binder) json2Codec(path string, src []byte) ([]byte, error) {
 		for k := range b.fields {
 			fields = append(fields, k)
 		}
-		return nil, fmt.Errorf("no field %s found in %s", path, fields)
+		return nil, nil, fmt.Errorf("no field %s found in %s", path, fields)
 	}
 	isDIR := strings.HasSuffix(field.Path, "/")
-	var data interface{}
-	if !isDIR {
-		data = newWithValue(field.NullValue, src, false)
-	} else {
-		data = newWithValue(field.ChildNullValue, src, false)
+	nullValue := field.NullValue
+	if isDIR {
+		nullValue = field.ChildNullValue
+	}
+	data, err := newWithValue(nullValue, src, false)
+	if err != nil {
+		return nil, nil, err
 	}
 	if data == nil {
-		return nil, nil
+		return nil, nil, nil
+	}
+	codec := b.fileCodec(filename)
+	fc, ok := codec.(FileCodec)
+	if !ok {
+		out, err := codec.Marshal(data)
+		return out, nil, err
+	}
+	var out []byte
+	if state != nil {
+		out, err = fc.MarshalFile(data, state)
+	} else {
+		out, err = fc.Marshal(data)
+	}
+	if err != nil {
+		return nil, nil, err
 	}
-	return b.codec.Marshal(data)
+	target, _ := newWithValue(nullValue, nil, true)
+	newState, err := fc.UnmarshalFile(out, target)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, newState, nil
 }
 
-func (b *Binder) codec2JSON(path string, src []byte) ([]byte, error) {
+// codec2JSON decode the file by its codec to json, and return the state of the file if the codec is a FileCodec
+func (b *Binder) codec2JSON(path, filename string, src []byte) ([]byte, interface{}, error) {
 	if b.codec == nil {
-		return src, nil
+		return src, nil, nil
 	}
 
 	var dir string
@@ -69,24 +151,32 @@ func (b *Binder) codec2JSON(path string, src []byte) ([]byte, error) {
 		for k := range b.fields {
 			fields = append(fields, k)
 		}
-		return nil, fmt.Errorf("can not found field %s in %s", path, fields)
+		return nil, nil, fmt.Errorf("can not found field %s in %s", path, fields)
 	}
 	isDIR := strings.HasSuffix(field.Path, "/")
 	var data interface{}
 	if !isDIR {
-		data = newWithValue(field.NullValue, nil, true)
+		data, _ = newWithValue(field.NullValue, nil, true)
+	} else {
+		data, _ = newWithValue(field.ChildNullValue, nil, true)
+	}
+	codec := b.fileCodec(filename)
+	var state interface{}
+	var err error
+	if fc, ok := codec.(FileCodec); ok {
+		state, err = fc.UnmarshalFile(src, data)
 	} else {
-		data = newWithValue(field.ChildNullValue, nil, true)
+		err = codec.Unmarshal(src, data)
 	}
-	err := b.codec.Unmarshal(src, data)
 	if err != nil {
-		return nil, fmt.Errorf("%s unmarshal path %s 's data %s error for %s", b.codec.String(), path, string(src), err)
+		return nil, nil, fmt.Errorf("%s unmarshal path %s 's data %s error for %s", codec.String(), path, string(src), err)
 	}
 	needRootUnmarshal := path == b.root
 	if needRootUnmarshal {
 		data = convertMainData(data, b.tagName)
 	}
-	return json.Marshal(data)
+	jsonData, err := json.Marshal(data)
+	return jsonData, state, err
 }
 
 type jsonCodec struct{}
@@ -159,3 +249,57 @@ func removePreLine(ret []byte) []byte {
 	}
 	return ret
 }
+
+// jsonDecoder marshal v to json and return the decoder of it, the numbers are decoded as json.Number, so the codecs
+// converting the values by their json form key the fields by their json names and keep the integers exact
+func jsonDecoder(v interface{}) (*json.Decoder, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec, nil
+}
+
+// jsonForm get the generic json value of v, the null members of the objects are removed and the other values are
+// converted by leaf, such as the numbers and the null elements of the arrays. It is nil when v is null
+func jsonForm(v interface{}, leaf func(v interface{}) (interface{}, error)) (interface{}, error) {
+	dec, err := jsonDecoder(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err = dec.Decode(&value); err != nil || value == nil {
+		return nil, err
+	}
+	return convertJSONForm(value, leaf)
+}
+
+func convertJSONForm(v interface{}, leaf func(v interface{}) (interface{}, error)) (interface{}, error) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, item := range x {
+			if item == nil {
+				delete(x, k)
+				continue
+			}
+			value, err := convertJSONForm(item, leaf)
+			if err != nil {
+				return nil, err
+			}
+			x[k] = value
+		}
+		return x, nil
+	case []interface{}:
+		for i, item := range x {
+			value, err := convertJSONForm(item, leaf)
+			if err != nil {
+				return nil, err
+			}
+			x[i] = value
+		}
+		return x, nil
+	}
+	return leaf(v)
+}
@@ -0,0 +1,93 @@
+package objectbind
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type codecTestServer struct {
+	Host    string        `json:"host"`
+	Port    int           `json:"port"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+// codecTestConf the fixture of the codec tests, the json tags are the keys of all the codecs
+type codecTestConf struct {
+	Name    string            `json:"name"`
+	Debug   bool              `json:"debug"`
+	Ratio   float64           `json:"ratio"`
+	Size    uint              `json:"size"`
+	Tags    []string          `json:"tags"`
+	Ports   []int             `json:"ports"`
+	Labels  map[string]string `json:"labels"`
+	Server  codecTestServer   `json:"server"`
+	Servers []codecTestServer `json:"servers"`
+}
+
+type codecRoundTripTest struct {
+	name string
+	conf codecTestConf
+}
+
+// codecRoundTripTests the values which every codec keeps through Marshal and Unmarshal
+var codecRoundTripTests = []codecRoundTripTest{
+	{name: "empty", conf: codecTestConf{}},
+	{name: "scalars", conf: codecTestConf{Name: "svc", Debug: true, Ratio: 0.25, Size: 7}},
+	{name: "special", conf: codecTestConf{Name: "a, \"b\" 'c' # d ; e = f: \\g\n\th é"}},
+	{name: "slices", conf: codecTestConf{Tags: []string{"a", "b"}, Ports: []int{80, 443}}},
+	{name: "map", conf: codecTestConf{Labels: map[string]string{"app": "svc", "tier": "web"}}},
+	{name: "nested", conf: codecTestConf{
+		Server:  codecTestServer{Host: "localhost", Port: 8080, Timeout: 1500 * time.Millisecond},
+		Servers: []codecTestServer{{Host: "a", Port: 1}, {Host: "b", Timeout: time.Minute}},
+	}},
+}
+
+// testCodecRoundTrip check that Unmarshal(Marshal(conf)) is conf
+func testCodecRoundTrip(t *testing.T, codec Codec, tests []codecRoundTripTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := codec.Marshal(tt.conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got codecTestConf
+			if err = codec.Unmarshal(data, &got); err != nil {
+				t.Fatalf("unmarshal %q error %s", data, err)
+			}
+			if !reflect.DeepEqual(got, tt.conf) {
+				t.Fatalf("got %+v, want %+v from\n%s", got, tt.conf, data)
+			}
+		})
+	}
+}
+
+// codecUnmarshalTest the data and its value, or the error of the malformed data
+type codecUnmarshalTest struct {
+	name string
+	data string
+	want codecTestConf
+	err  string
+}
+
+func testCodecUnmarshal(t *testing.T, codec Codec, tests []codecUnmarshalTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got codecTestConf
+			err := codec.Unmarshal([]byte(tt.data), &got)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package objectbind
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PatchOp the operation of RFC 6902 JSON Patch
+type PatchOp struct {
+	Op   string
+	Path string
+	// From the source path of the move and copy operations
+	From  string
+	Value interface{}
+	// Field the path of the changed field in the syntax of BindField, such as Feature.Limit or DataMapRoot[a], the "]"
+	// and "\" of the map keys are escaped by "\"
+	Field string
+	// Old the value before the change
+	Old interface{}
+}
+
+// MarshalJSON marshal the operation as RFC 6902, the value is omitted by the remove, move and copy operations
+func (op PatchOp) MarshalJSON() ([]byte, error) {
+	switch op.Op {
+	case PatchRemove:
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{op.Op, op.Path})
+	case PatchMove, PatchCopy:
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			From string `json:"from"`
+			Path string `json:"path"`
+		}{op.Op, op.From, op.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{op.Op, op.Path, op.Value})
+}
+
+// UnmarshalJSON unmarshal the operation of RFC 6902, the value is required by the add, replace and test operations
+func (op *PatchOp) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Op    string           `json:"op"`
+		Path  *string          `json:"path"`
+		From  string           `json:"from"`
+		Value *json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Path == nil {
+		return fmt.Errorf("the path of the %s operation is missing", v.Op)
+	}
+	*op = PatchOp{Op: v.Op, Path: *v.Path, From: v.From}
+	switch v.Op {
+	case PatchAdd, PatchReplace, PatchTest:
+		if v.Value == nil {
+			return fmt.Errorf("the value of the %s operation is missing", v.Op)
+		}
+		return json.Unmarshal(*v.Value, &op.Value)
+	case PatchRemove, PatchMove, PatchCopy:
+		return nil
+	}
+	return fmt.Errorf("the operation %s is not supported", v.Op)
+}
+
+func (op PatchOp) String() string {
+	switch op.Op {
+	case PatchAdd:
+		return op.Field + ": added " + jsonString(op.Value)
+	case PatchRemove:
+		return op.Field + ": removed " + jsonString(op.Old)
+	}
+	return op.Field + ": " + jsonString(op.Old) + " -> " + jsonString(op.Value)
+}
+
+// the operations of the patch
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchMove    = "move"
+	PatchCopy    = "copy"
+	PatchTest    = "test"
+)
+
+// Patch the RFC 6902 JSON Patch between two values of the bound object
+type Patch []PatchOp
+
+// Fields get the paths of the changed fields in the syntax of BindField
+func (p Patch) Fields() []string {
+	fields := make([]string, 0, len(p))
+	for _, op := range p {
+		fields = append(fields, op.Field)
+	}
+	return fields
+}
+
+func (p Patch) String() string {
+	ops := make([]string, 0, len(p))
+	for _, op := range p {
+		ops = append(ops, op.String())
+	}
+	return strings.Join(ops, ", ")
+}
+
+// diff get the patch from pre to current, the values are compared in their json form
+func diff(pre, current interface{}) (Patch, error) {
+	preValue, err := jsonValue(pre)
+	if err != nil {
+		return nil, err
+	}
+	value, err := jsonValue(current)
+	if err != nil {
+		return nil, err
+	}
+	var p Patch
+	diffValue(&p, reflect.TypeOf(current), "", "", preValue, value)
+	return p, nil
+}
+
+func diffValue(p *Patch, t reflect.Type, path, field string, pre, current interface{}) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch c := current.(type) {
+	case map[string]interface{}:
+		if m, ok := pre.(map[string]interface{}); ok {
+			diffMap(p, t, path, field, m, c)
+			return
+		}
+	case []interface{}:
+		if s, ok := pre.([]interface{}); ok {
+			diffSlice(p, t, path, field, s, c)
+			return
+		}
+	}
+	if !reflect.DeepEqual(pre, current) {
+		*p = append(*p, PatchOp{Op: PatchReplace, Path: path, Value: current, Field: field, Old: pre})
+	}
+}
+
+func diffMap(p *Patch, t reflect.Type, path, field string, pre, current map[string]interface{}) {
+	keys := make([]string, 0, len(current))
+	for k := range current {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		ft, f := childField(t, field, k)
+		kp := path + "/" + escapePointer(k)
+		preValue, ok := pre[k]
+		if !ok {
+			*p = append(*p, PatchOp{Op: PatchAdd, Path: kp, Value: current[k], Field: f})
+			continue
+		}
+		diffValue(p, ft, kp, f, preValue, current[k])
+	}
+	keys = keys[:0]
+	for k := range pre {
+		if _, ok := current[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, f := childField(t, field, k)
+		*p = append(*p, PatchOp{Op: PatchRemove, Path: path + "/" + escapePointer(k), Field: f, Old: pre[k]})
+	}
+}
+
+func diffSlice(p *Patch, t reflect.Type, path, field string, pre, current []interface{}) {
+	var et reflect.Type
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		et = t.Elem()
+	}
+	for i, v := range current {
+		ip := path + "/" + strconv.Itoa(i)
+		f := field + "[" + strconv.Itoa(i) + "]"
+		if i >= len(pre) {
+			*p = append(*p, PatchOp{Op: PatchAdd, Path: ip, Value: v, Field: f})
+			continue
+		}
+		diffValue(p, et, ip, f, pre[i], v)
+	}
+	// remove from the end so that the indexes of the following operations are valid
+	for i := len(pre) - 1; i >= len(current); i-- {
+		*p = append(*p, PatchOp{Op: PatchRemove, Path: path + "/" + strconv.Itoa(i), Field: field + "[" + strconv.Itoa(i) + "]", Old: pre[i]})
+	}
+}
+
+// childField get the type and the field path of the json key of t
+func childField(t reflect.Type, field, key string) (reflect.Type, string) {
+	if t != nil && t.Kind() == reflect.Struct {
+		if sf, ok := jsonField(t, key); ok {
+			if field == "" {
+				return sf.Type, sf.Name
+			}
+			return sf.Type, field + "." + sf.Name
+		}
+	}
+	var et reflect.Type
+	if t != nil && t.Kind() == reflect.Map {
+		et = t.Elem()
+	}
+	return et, field + "[" + escapeKey(key) + "]"
+}
+
+// jsonField get the struct field by its json name, include the fields of the embedded structs
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagName := strings.Split(tag, ",")[0]
+		if sf.Anonymous && tagName == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if f, ok := jsonField(ft, name); ok {
+					return f, true
+				}
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if tagName == name || (tagName == "" && strings.EqualFold(sf.Name, name)) {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// jsonValue convert v to the generic json value
+func jsonValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func jsonString(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "<invalid>"
+	}
+	return string(data)
+}
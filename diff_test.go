@@ -0,0 +1,133 @@
+package objectbind
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type diffTestItem struct {
+	Name  string `json:"name"`
+	Ports []int  `json:"ports,omitempty"`
+}
+
+type diffTestConf struct {
+	Title  string                   `json:"title"`
+	Server diffTestItem             `json:"server"`
+	Items  []diffTestItem           `json:"items"`
+	Labels map[string]string       `json:"labels"`
+	Groups map[string]*diffTestItem `json:"groups"`
+	Hidden string                   `json:"-"`
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "", want: nil},
+		{path: "A", want: []string{"A"}},
+		{path: "A.B", want: []string{"A", "B"}},
+		{path: "A[0][1]", want: []string{"A", "0", "1"}},
+		{path: "A[0].B", want: []string{"A", "0", "B"}},
+		{path: "Services.*.Endpoints", want: []string{"Services", "*", "Endpoints"}},
+		{path: "Map[*]", want: []string{"Map", "*"}},
+		{path: "Map[a.b].C", want: []string{"Map", "a.b", "C"}},
+		{path: `Map[x\]y][z]`, want: []string{"Map", "x]y", "z"}},
+		{path: `Map[a\\][[b]`, want: []string{"Map", `a\`, "[b"}},
+		{path: "[k].A", want: []string{"k", "A"}},
+	}
+	for _, tt := range tests {
+		if got := compile(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("compile(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	base := func() diffTestConf {
+		return diffTestConf{
+			Title:  "a",
+			Server: diffTestItem{Name: "s", Ports: []int{80}},
+			Items:  []diffTestItem{{Name: "i0", Ports: []int{1, 2}}, {Name: "i1"}, {Name: "i2"}},
+			Labels: map[string]string{"a": "1", "b": "2"},
+			Groups: map[string]*diffTestItem{"g.1": {Name: "g"}},
+		}
+	}
+	tests := []struct {
+		name   string
+		change func(c *diffTestConf)
+		// want the operations as "op path field"
+		want []string
+	}{
+		{name: "equal", change: func(c *diffTestConf) {}},
+		{name: "hidden", change: func(c *diffTestConf) { c.Hidden = "h" }},
+		{name: "modified", change: func(c *diffTestConf) { c.Title = "b" }, want: []string{"replace /title Title"}},
+		{
+			name: "nested struct", change: func(c *diffTestConf) { c.Server.Name, c.Server.Ports = "t", append(c.Server.Ports, 443) },
+			want: []string{"replace /server/name Server.Name", "add /server/ports/1 Server.Ports[1]"},
+		},
+		{
+			name: "slice added", change: func(c *diffTestConf) { c.Items = append(c.Items, diffTestItem{Name: "i3"}) },
+			want: []string{"add /items/3 Items[3]"},
+		},
+		{
+			name: "slice removed", change: func(c *diffTestConf) { c.Items = c.Items[:1] },
+			want: []string{"remove /items/2 Items[2]", "remove /items/1 Items[1]"},
+		},
+		{
+			name: "slice modified", change: func(c *diffTestConf) { c.Items[0].Ports = []int{1, 3} },
+			want: []string{"replace /items/0/ports/1 Items[0].Ports[1]"},
+		},
+		{
+			name: "map", change: func(c *diffTestConf) { c.Labels = map[string]string{"a": "3", "c": "4"} },
+			want: []string{"replace /labels/a Labels[a]", "add /labels/c Labels[c]", "remove /labels/b Labels[b]"},
+		},
+		{
+			name: "map keys with separators",
+			change: func(c *diffTestConf) {
+				c.Labels["a.b"], c.Labels["x]y"], c.Labels[`p/q~r\`] = "1", "2", "3"
+			},
+			want: []string{"add /labels/a.b Labels[a.b]", `add /labels/p~1q~0r\ Labels[p/q~r\\]`, `add /labels/x]y Labels[x\]y]`},
+		},
+		{
+			name: "map of pointers", change: func(c *diffTestConf) { c.Groups["g.1"].Name = "h" },
+			want: []string{"replace /groups/g.1/name Groups[g.1].Name"},
+		},
+		{
+			name: "nil map", change: func(c *diffTestConf) { c.Labels = nil },
+			want: []string{"replace /labels Labels"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pre, current := base(), base()
+			tt.change(&current)
+			p, err := diff(&pre, &current)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, op := range p {
+				got = append(got, fmt.Sprintf("%s %s %s", op.Op, op.Path, op.Field))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			// the fields of the operations get the values of the operations by getFieldValue
+			for _, op := range p {
+				src, want := interface{}(&current), op.Value
+				if op.Op == PatchRemove {
+					src, want = &pre, op.Old
+				}
+				v, err := getFieldValue(src, op.Field, false, false)
+				if err != nil {
+					t.Fatalf("get %s error %s", op.Field, err)
+				}
+				if value, _ := jsonValue(v); !reflect.DeepEqual(value, want) {
+					t.Fatalf("got %s of %s, want %s", jsonString(value), op.Field, jsonString(want))
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,62 @@
 package objectbind
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 // ErrNoFiles return no files error when you call ForceLoad
 var ErrNoFiles = errors.New("no files")
+
+// ErrConflict return by Save when the files are changed in the backend after they are loaded
+type ErrConflict struct {
+	// Keys the conflicting paths of the backend
+	Keys []string
+}
+
+func (e *ErrConflict) Error() string {
+	return "conflict on " + strings.Join(e.Keys, ", ")
+}
+
+// Conflicts get the conflicting keys
+func (e *ErrConflict) Conflicts() []string {
+	return e.Keys
+}
+
+// conflictKeys get the conflicting keys if err is a conflict error of the backends
+func conflictKeys(err error) ([]string, bool) {
+	var conflict interface {
+		Conflicts() []string
+	}
+	if errors.As(err, &conflict) {
+		return conflict.Conflicts(), true
+	}
+	return nil, false
+}
+
+// ValidationError the error of the data rejected by the validators
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return "validate error for " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// decodeError the error of the file which can not be decoded by the codec
+type decodeError struct {
+	Path string
+	Err  error
+}
+
+func (e *decodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.Err
+}
@@ -2,8 +2,11 @@ package etcd
 
 import (
 	"context"
+	"fmt"
 	"net/url"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,6 +26,7 @@ func init() {
 type Etcd struct {
 	client *clientv3.Client
 	Root   string
+	logger objectbind.Logger
 }
 
 // New new etcd client
@@ -62,6 +66,7 @@ func New(ctx context.Context, uri *url.URL) (*Etcd, error) {
 	return &Etcd{
 		client: cli,
 		Root:   filepath.Dir(uri.Path) + "/",
+		logger: objectbind.LoggerFromContext(ctx),
 	}, nil
 }
 
@@ -86,6 +91,112 @@ func (e *Etcd) Load(ctx context.Context, path string) (map[string][]byte, error)
 	return data, nil
 }
 
+// LoadRevision load data from path with the mod revisions of the keys
+func (e *Etcd) LoadRevision(ctx context.Context, path string) (map[string][]byte, map[string]string, error) {
+	var opts []clientv3.OpOption
+	if strings.HasSuffix(path, "/") {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	getResp, err := e.client.Get(ctx, path, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	data := make(map[string][]byte)
+	revisions := make(map[string]string)
+	for _, kv := range getResp.Kvs {
+		data[string(kv.Key)] = kv.Value
+		revisions[string(kv.Key)] = strconv.FormatInt(kv.ModRevision, 10)
+	}
+	return data, revisions, nil
+}
+
+// SaveRevision save the data to path in a transaction if the mod revision of path is not changed
+func (e *Etcd) SaveRevision(ctx context.Context, path string, data []byte, revision string) (string, error) {
+	cmp, err := revisionCompare(path, revision)
+	if err != nil {
+		return "", err
+	}
+	var op clientv3.Op
+	if len(data) > 0 {
+		op = clientv3.OpPut(path, string(data))
+	} else {
+		op = clientv3.OpDelete(path)
+	}
+	resp, err := e.client.Txn(ctx).If(cmp).Then(op).Commit()
+	if err != nil {
+		return "", err
+	}
+	if !resp.Succeeded {
+		return "", &objectbind.ErrConflict{Keys: []string{path}}
+	}
+	if len(data) == 0 {
+		return "", nil
+	}
+	return strconv.FormatInt(resp.Header.Revision, 10), nil
+}
+
+// SaveTx save the data of all the paths in a transaction
+func (e *Etcd) SaveTx(ctx context.Context, data map[string][]byte, revisions map[string]string) (map[string]string, error) {
+	paths := make([]string, 0, len(data))
+	for path := range data {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var cmps []clientv3.Cmp
+	var ops, elseOps []clientv3.Op
+	for _, path := range paths {
+		if revisions != nil {
+			cmp, err := revisionCompare(path, revisions[path])
+			if err != nil {
+				return nil, err
+			}
+			cmps = append(cmps, cmp)
+			elseOps = append(elseOps, clientv3.OpGet(path))
+		}
+		if len(data[path]) > 0 {
+			ops = append(ops, clientv3.OpPut(path, string(data[path])))
+		} else {
+			ops = append(ops, clientv3.OpDelete(path))
+		}
+	}
+	resp, err := e.client.Txn(ctx).If(cmps...).Then(ops...).Else(elseOps...).Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Succeeded {
+		var conflicts []string
+		for i, path := range paths {
+			var current string
+			if kvs := resp.Responses[i].GetResponseRange().GetKvs(); len(kvs) > 0 {
+				current = strconv.FormatInt(kvs[0].ModRevision, 10)
+			}
+			if current != revisions[path] {
+				conflicts = append(conflicts, path)
+			}
+		}
+		return nil, &objectbind.ErrConflict{Keys: conflicts}
+	}
+	newRevisions := make(map[string]string, len(paths))
+	revision := strconv.FormatInt(resp.Header.Revision, 10)
+	for _, path := range paths {
+		if len(data[path]) > 0 {
+			newRevisions[path] = revision
+		}
+	}
+	return newRevisions, nil
+}
+
+func revisionCompare(path, revision string) (clientv3.Cmp, error) {
+	if revision == "" {
+		return clientv3.Compare(clientv3.CreateRevision(path), "=", 0), nil
+	}
+	rev, err := strconv.ParseInt(revision, 10, 64)
+	if err != nil {
+		return clientv3.Cmp{}, fmt.Errorf("invalid revision %s of %s", revision, path)
+	}
+	return clientv3.Compare(clientv3.ModRevision(path), "=", rev), nil
+}
+
 // Save save the data to path
 func (e *Etcd) Save(ctx context.Context, path string, data []byte) (err error) {
 	if len(data) > 0 {
@@ -98,20 +209,87 @@ func (e *Etcd) Save(ctx context.Context, path string, data []byte) (err error) {
 
 // Watch watch the path
 func (e *Etcd) Watch(ctx context.Context, paths []string, onChange func(data map[string][]byte)) error {
+	return e.WatchStatus(ctx, paths, onChange, nil)
+}
+
+const (
+	// watchRetryInterval the interval to restart the broken watch
+	watchRetryInterval = time.Second
+	// progressInterval the interval to request the progress of the watch as its heartbeat
+	progressInterval = 10 * time.Second
+)
+
+// WatchStatus watch the paths as Watch, the broken watches, such as the compacted or the leader lost watches,
+// are restarted and reported to onStatus, the progress notifications of the watches are reported as heartbeats
+func (e *Etcd) WatchStatus(ctx context.Context, paths []string, onChange func(data map[string][]byte), onStatus func(connected bool, err error)) error {
 	paths = commonPaths(paths)
 	for _, key := range paths {
-		var opts []clientv3.OpOption
+		opts := []clientv3.OpOption{clientv3.WithProgressNotify()}
 		if strings.HasSuffix(key, "/") {
 			opts = append(opts, clientv3.WithPrefix())
 		}
-		watchChan := e.client.Watch(ctx, key, opts...)
-		go watch(watchChan, onChange)
+		go e.watch(ctx, key, opts, onChange, onStatus)
 	}
 	return nil
 }
 
-func watch(watchChan clientv3.WatchChan, onChange func(data map[string][]byte)) {
-	for watchResponse := range watchChan {
+func (e *Etcd) watch(ctx context.Context, key string, opts []clientv3.OpOption, onChange func(data map[string][]byte), onStatus func(connected bool, err error)) {
+	for restarted := false; ; restarted = true {
+		watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+		watchChan := e.client.Watch(watchCtx, key, opts...)
+		if restarted && onStatus != nil {
+			onStatus(true, nil)
+		}
+		err := e.watchChanges(watchCtx, watchChan, onChange, onStatus)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("the watch of %s is closed", key)
+		}
+		e.logger.Warn("etcd: watch error", "op", "watch", "path", key, "backend", "etcd", "error", err)
+		if onStatus != nil {
+			onStatus(false, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+// watchChanges send the changes of watchChan to onChange until it is closed or broken,
+// the progress of the watch is requested periodically and reported to onStatus
+func (e *Etcd) watchChanges(ctx context.Context, watchChan clientv3.WatchChan, onChange func(data map[string][]byte), onStatus func(connected bool, err error)) error {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		var watchResponse clientv3.WatchResponse
+		select {
+		case <-ticker.C:
+			if onStatus != nil {
+				if err := e.client.RequestProgress(ctx); err != nil {
+					e.logger.Warn("etcd: request progress error", "op", "watch", "backend", "etcd", "error", err)
+				}
+			}
+			continue
+		case resp, ok := <-watchChan:
+			if !ok {
+				return nil
+			}
+			watchResponse = resp
+		}
+		if err := watchResponse.Err(); err != nil {
+			return err
+		}
+		if watchResponse.IsProgressNotify() {
+			if onStatus != nil {
+				onStatus(true, nil)
+			}
+			continue
+		}
 		var isChange bool
 		for _, ev := range watchResponse.Events {
 			if ev.Type == clientv3.EventTypePut || ev.Type == clientv3.EventTypeDelete {
@@ -130,6 +308,11 @@ func watch(watchChan clientv3.WatchChan, onChange func(data map[string][]byte))
 	}
 }
 
+// HeartbeatInterval the progress of the watches is reported as their heartbeat in the interval
+func (e *Etcd) HeartbeatInterval() time.Duration {
+	return progressInterval
+}
+
 // Close close the etcd
 func (e *Etcd) Close(_ context.Context) error {
 	return e.client.Close()
@@ -0,0 +1,134 @@
+package objectbind
+
+import (
+	"errors"
+	"time"
+)
+
+// EventType the type of the Event
+type EventType int
+
+const (
+	// EventLoaded all the files are loaded from the backend by Bind, ForceLoad or the ttl reloading
+	EventLoaded EventType = iota + 1
+	// EventChanged the changed files from the watch are applied to the bound object
+	EventChanged
+	// EventDecodeFailed the files can not be decoded, the bound object is not changed. The removed and emptied files are
+	// not decode failures, they are applied as the changes
+	EventDecodeFailed
+	// EventRejected the decoded files are rejected by the validators, the bound object is not changed
+	EventRejected
+	// EventBackendError the backend fails to load the files or its watch is broken
+	EventBackendError
+	// EventWatchRestarted the broken watch of the backend is restarted, the files are reloaded after it
+	EventWatchRestarted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventLoaded:
+		return "Loaded"
+	case EventChanged:
+		return "Changed"
+	case EventDecodeFailed:
+		return "DecodeFailed"
+	case EventRejected:
+		return "Rejected"
+	case EventBackendError:
+		return "BackendError"
+	case EventWatchRestarted:
+		return "WatchRestarted"
+	}
+	return "Unknown"
+}
+
+// Event the event of loading and watching the backend
+type Event struct {
+	Type EventType
+	// Paths the paths of the backend, the directories end with "/"
+	Paths []string
+	// Err the underlying error of the failed events
+	Err error
+	// Patch the patch of the bound object by the Loaded and Changed events, it is nil if the object is not changed
+	Patch Patch
+	Time  time.Time
+}
+
+// eventBufferSize the events are dropped when the buffer of Events is full
+const eventBufferSize = 64
+
+// Events return the channel of the events, the events are dropped when the channel is full and the channel is closed
+// when the binder is closed
+func (b *Binder) Events() <-chan Event {
+	return b.events
+}
+
+// emit send the event without blocking, it is safe to call it under the binder lock
+func (b *Binder) emit(typ EventType, paths []string, err error) {
+	b.send(Event{Type: typ, Paths: paths, Err: err, Time: time.Now()})
+}
+
+// emitPatch send the event with the patch of the change
+func (b *Binder) emitPatch(typ EventType, paths []string, patch Patch) {
+	b.send(Event{Type: typ, Paths: paths, Patch: patch, Time: time.Now()})
+}
+
+func (b *Binder) send(ev Event) {
+	b.updateStatus(&ev)
+	b.eventMu.RLock()
+	defer b.eventMu.RUnlock()
+	if b.eventsClosed {
+		return
+	}
+	select {
+	case b.events <- ev:
+	default:
+		b.logger.Debug("objectbind: drop the event for the events channel is full", "op", "event", "event", ev.Type.String())
+	}
+}
+
+// closeEvents close the events channel, no event is sent after it
+func (b *Binder) closeEvents() {
+	b.eventMu.Lock()
+	defer b.eventMu.Unlock()
+	if !b.eventsClosed {
+		b.eventsClosed = true
+		close(b.events)
+	}
+}
+
+// decodeEventType get the type of the event for the error of decode
+func decodeEventType(err error) EventType {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return EventRejected
+	}
+	return EventDecodeFailed
+}
+
+// loadEvent get the type and the paths of the event for the error of loading path
+func (b *Binder) loadEvent(path string, err error) (EventType, []string) {
+	var decodeErr *decodeError
+	if errors.As(err, &decodeErr) {
+		return EventDecodeFailed, []string{decodeErr.Path}
+	}
+	return EventBackendError, []string{b.getFileName(path)}
+}
+
+// filePaths convert the keys of the files to the paths of the backend
+func (b *Binder) filePaths(keys []string) []string {
+	paths := make([]string, 0, len(keys))
+	for _, k := range keys {
+		paths = append(paths, b.getFileName(k))
+	}
+	return paths
+}
+
+// fileKeys get the keys of the files
+func fileKeys(files []*mapData) []string {
+	keys := make([]string, 0, len(files))
+	for _, f := range files {
+		keys = append(keys, f.Key)
+	}
+	return keys
+}
@@ -2,32 +2,85 @@ package file
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/fsnotify/fsnotify"
 )
 
 // File the file watcher
 type File struct {
-	watcher         *fsnotify.Watcher
+	mu              sync.Mutex
+	watchers        []*fsnotify.Watcher
 	wd              string
 	wdLen           int
 	basedOnRootPath bool
+	mode            os.FileMode
+	dirMode         os.FileMode
+	logger          Logger
+	// rename rename the staged files of SaveTx, the tests replace it to make renaming fail
+	rename func(oldPath, newPath string) error
 }
 
-// New new file client
-func New(ctx context.Context, u *url.URL) (*File, error) {
+// Option the option of the file client
+type Option func(*File)
+
+// WithMode the mode of the new files, default is 0644, the mode query of the url does the same
+func WithMode(mode os.FileMode) Option {
+	return func(f *File) {
+		f.mode = mode
+	}
+}
+
+// WithDirMode the mode of the created dirs, default is 0755, the dirmode query of the url does the same
+func WithDirMode(mode os.FileMode) Option {
+	return func(f *File) {
+		f.dirMode = mode
+	}
+}
+
+// WithLogger the logger of the file client, default writes json lines to stderr
+func WithLogger(l Logger) Option {
+	return func(f *File) {
+		f.logger = l
+	}
+}
+
+// New new file client, such as file:///etc/app/conf.yaml?mode=0640&dirmode=0750
+func New(ctx context.Context, u *url.URL, opts ...Option) (*File, error) {
 	wd, err := os.Getwd()
 	if err != nil {
 		return nil, err
 	}
-	fb := &File{}
+	fb := &File{
+		mode:    0644,
+		dirMode: 0755,
+		logger:  NewJSONLogger(os.Stderr),
+		rename:  os.Rename,
+	}
+	query := u.Query()
+	if m := query.Get("mode"); m != "" {
+		if fb.mode, err = parseMode(m); err != nil {
+			return nil, fmt.Errorf("invalid mode %s for %s", m, err)
+		}
+	}
+	if m := query.Get("dirmode"); m != "" {
+		if fb.dirMode, err = parseMode(m); err != nil {
+			return nil, fmt.Errorf("invalid dirmode %s for %s", m, err)
+		}
+	}
+	for _, o := range opts {
+		o(fb)
+	}
 	path := u.Host + u.Path
 	if strings.HasPrefix(path, "/") {
 		fb.basedOnRootPath = true
@@ -40,6 +93,14 @@ func New(ctx context.Context, u *url.URL) (*File, error) {
 	return fb, nil
 }
 
+func parseMode(s string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	return os.FileMode(m) & os.ModePerm, nil
+}
+
 func (f *File) getPath(p string) string {
 	if f.basedOnRootPath || !strings.HasPrefix(p, "/") {
 		return p
@@ -96,10 +157,185 @@ func (f *File) Load(ctx context.Context, path string) (data map[string][]byte, e
 	return
 }
 
+// LoadRevision load data from path with the revisions of the files
+func (f *File) LoadRevision(ctx context.Context, path string) (data map[string][]byte, revisions map[string]string, err error) {
+	data, err = f.Load(ctx, path)
+	if err != nil {
+		return
+	}
+	revisions = make(map[string]string, len(data))
+	for k, v := range data {
+		info, statErr := os.Stat(k)
+		if statErr != nil {
+			continue
+		}
+		revisions[k] = revision(info, v)
+	}
+	return
+}
+
+// SaveRevision save data to path if the revision of the file is not changed
+func (f *File) SaveRevision(ctx context.Context, path string, data []byte, revision string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	current, err := readRevision(path)
+	if err != nil {
+		return "", err
+	}
+	if current != revision {
+		return "", &ConflictError{Paths: []string{path}}
+	}
+	if err = f.Save(ctx, path, data); err != nil {
+		return "", err
+	}
+	if len(data) == 0 {
+		return "", nil
+	}
+	return readRevision(path)
+}
+
+// SaveTx save the files all or nothing, the files are written to temp files and renamed after all of them are written,
+// the renamed files are restored if renaming fails
+func (f *File) SaveTx(_ context.Context, data map[string][]byte, revisions map[string]string) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	paths := make([]string, 0, len(data))
+	for path := range data {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if revisions != nil {
+		var conflicts []string
+		for _, path := range paths {
+			current, err := readRevision(path)
+			if err != nil {
+				return nil, err
+			}
+			if current != revisions[path] {
+				conflicts = append(conflicts, path)
+			}
+		}
+		if len(conflicts) > 0 {
+			return nil, &ConflictError{Paths: conflicts}
+		}
+	}
+	staged := make([]*stagedFile, 0, len(paths))
+	cleanup := func() {
+		for _, v := range staged {
+			if v.tmp != "" {
+				_ = os.Remove(v.tmp)
+			}
+		}
+	}
+	for _, path := range paths {
+		v := &stagedFile{path: path}
+		staged = append(staged, v)
+		backup, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			cleanup()
+			return nil, err
+		}
+		v.backup, v.existed = backup, err == nil
+		if len(data[path]) == 0 {
+			continue
+		}
+		if v.tmp, v.target, err = f.writeTemp(path, data[path]); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+	for i, v := range staged {
+		var err error
+		if v.tmp != "" {
+			err = f.rename(v.tmp, v.target)
+			if err == nil {
+				v.tmp = ""
+			}
+		} else if err = os.Remove(v.path); os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			cleanup()
+			f.rollback(staged[:i])
+			return nil, fmt.Errorf("save %s error for %s", v.path, err)
+		}
+	}
+	newRevisions := make(map[string]string, len(paths))
+	for _, path := range paths {
+		if len(data[path]) == 0 {
+			continue
+		}
+		revision, err := readRevision(path)
+		if err != nil {
+			return nil, err
+		}
+		newRevisions[path] = revision
+	}
+	return newRevisions, nil
+}
+
+type stagedFile struct {
+	path    string
+	target  string
+	tmp     string
+	backup  []byte
+	existed bool
+}
+
+// rollback restore the saved files
+func (f *File) rollback(files []*stagedFile) {
+	for _, v := range files {
+		var err error
+		if v.existed {
+			err = f.writeFile(v.path, v.backup)
+		} else if err = os.Remove(v.path); os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			f.logger.Warn("file: rollback error", "op", "rollback", "path", v.path, "backend", "file", "error", err)
+		}
+	}
+}
+
+// ConflictError the error of SaveRevision and SaveTx when the files are changed
+type ConflictError struct {
+	Paths []string
+}
+
+func (e *ConflictError) Error() string {
+	return "files " + strings.Join(e.Paths, ", ") + " are changed"
+}
+
+// Conflicts get the conflicting paths
+func (e *ConflictError) Conflicts() []string {
+	return e.Paths
+}
+
+func readRevision(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return revision(info, data), nil
+}
+
+// revision the revision of the file is made of its modification time and content hash
+func revision(info os.FileInfo, data []byte) string {
+	sum := sha256.Sum256(data)
+	return strconv.FormatInt(info.ModTime().UnixNano(), 10) + "-" + hex.EncodeToString(sum[:8])
+}
+
 // Save save data to path
 func (f *File) Save(_ context.Context, path string, data []byte) error {
 	if len(data) > 0 {
-		return writeFile(path, data)
+		return f.writeFile(path, data)
 	}
 	err := os.Remove(path)
 	if os.IsNotExist(err) {
@@ -108,70 +344,215 @@ func (f *File) Save(_ context.Context, path string, data []byte) error {
 	return err
 }
 
-func writeFile(path string, data []byte) error {
-	err := ioutil.WriteFile(path, data, 0700)
-	if os.IsNotExist(err) {
-		fileDir := filepath.Dir(path)
-		if _, pathStatErr := os.Stat(fileDir); pathStatErr != nil {
-			if !os.IsNotExist(pathStatErr) {
-				return fmt.Errorf("try to open file error %s, try to stat dir %s,  error %s", err, fileDir, pathStatErr)
+// writeFile write the file atomically by renaming a written temp file to path
+func (f *File) writeFile(path string, data []byte) error {
+	tmp, target, err := f.writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, target); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("rename %s to %s error for %s", tmp, target, err)
+	}
+	return nil
+}
+
+// writeTemp write and sync data to a hidden temp file in the dir of the real file of path, and return the real file
+// which the temp file should be renamed to, so the symlinks are kept. The temp file has the mode of the existing file,
+// the mode of the options is only used for the new files
+func (f *File) writeTemp(path string, data []byte) (tmpPath, target string, err error) {
+	target = path
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		target = real
+	}
+	mode := f.mode
+	if info, err := os.Stat(target); err == nil {
+		mode = info.Mode().Perm()
+	}
+	dir, name := filepath.Split(target)
+	if dir == "" {
+		dir = "."
+	}
+	if err = os.MkdirAll(dir, f.dirMode); err != nil {
+		return "", "", fmt.Errorf("try to mkdir %s error %s", dir, err)
+	}
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return "", "", err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", "", err
+	}
+	return tmp.Name(), target, nil
+}
+
+// Watch watch the path, the dirs of the files are watched so that the files replaced by renaming are still watched
+func (f *File) Watch(ctx context.Context, paths []string, onChange func(map[string][]byte)) error {
+	return f.WatchStatus(ctx, paths, onChange, nil)
+}
+
+// WatchStatus watch the paths as Watch, onStatus is called with the error of fsnotify, then it is called with connected
+// after the error because the events may be lost, so that the watched files can be reloaded
+func (f *File) WatchStatus(ctx context.Context, paths []string, onChange func(map[string][]byte), onStatus func(connected bool, err error)) (err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("fsnotify.NewWatcher error for %s", err)
+	}
+	w := &watchPaths{
+		files: make(map[string]string),
+		dirs:  make(map[string]string),
+		known: make(map[string]map[string]bool),
+	}
+	for _, v := range paths {
+		dir := v
+		if strings.HasSuffix(v, "/") {
+			w.dirs[filepath.Clean(v)] = v
+		} else {
+			w.files[filepath.Clean(v)] = v
+			dir, _ = filepath.Split(v)
+		}
+		if dir == "" {
+			dir = "./"
+		}
+		err = watcher.Add(dir)
+		if os.IsNotExist(err) {
+			err = os.MkdirAll(dir, f.dirMode)
+			if err == nil {
+				err = watcher.Add(dir)
 			}
-			if mkdirError := os.MkdirAll(fileDir, os.FileMode(0700)); mkdirError != nil {
-				return fmt.Errorf("try to open file %s, try to mkdir %s,  error %s", err, path, mkdirError)
+		}
+		if err != nil {
+			_ = watcher.Close()
+			return fmt.Errorf("fsnotify.Add %s error for %s", dir, err)
+		}
+		if strings.HasSuffix(v, "/") && isDataDir(v) {
+			files, loadErr := f.Load(ctx, v)
+			if loadErr != nil {
+				_ = watcher.Close()
+				return loadErr
 			}
+			w.setKnown(filepath.Clean(v), files)
 		}
-		err = ioutil.WriteFile(path, data, 0700)
 	}
+	f.mu.Lock()
+	f.watchers = append(f.watchers, watcher)
+	f.mu.Unlock()
+	go f.watch(ctx, watcher, w, onChange, onStatus)
 	return err
 }
 
-// Watch watch the path
-func (f *File) Watch(ctx context.Context, paths []string, onChange func(map[string][]byte)) (err error) {
-	if f.watcher == nil {
-		f.watcher, err = fsnotify.NewWatcher()
-		if err != nil {
-			return fmt.Errorf("fsnotify.NewWatcher error for %s", err)
+// dataDir the symlink to the data dir of the ConfigMap and Secret volumes of kubernetes,
+// the volumes are updated by swapping it atomically
+const dataDir = "..data"
+
+// isDataDir check if dir is in the layout of the kubernetes volumes
+func isDataDir(dir string) bool {
+	info, err := os.Lstat(filepath.Join(dir, dataDir))
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// watchPaths the watched files and dirs by their clean paths
+type watchPaths struct {
+	files map[string]string
+	dirs  map[string]string
+	// known the loaded files of the watched dirs in the layout of the kubernetes volumes
+	known map[string]map[string]bool
+}
+
+func (w *watchPaths) setKnown(dir string, files map[string][]byte) {
+	known := make(map[string]bool, len(files))
+	for k := range files {
+		known[k] = true
+	}
+	w.known[dir] = known
+}
+
+// path get the watched path of the event name
+func (w *watchPaths) path(name string) (string, bool) {
+	name = filepath.Clean(name)
+	if path, ok := w.files[name]; ok {
+		return path, true
+	}
+	dir, filename := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+	if path, ok := w.dirs[filepath.Clean(dir)]; ok {
+		return path + filename, true
+	}
+	return "", false
+}
+
+// Close close all the watchers
+func (f *File) Close(_ context.Context) (err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, watcher := range f.watchers {
+		if closeErr := watcher.Close(); closeErr != nil {
+			err = closeErr
 		}
 	}
-	for _, v := range paths {
-		if v == "" {
-			v = "./"
+	f.watchers = nil
+	return
+}
+
+// swapData read all the watched files in dir after its data dir is swapped, the removed files are nil
+func (f *File) swapData(ctx context.Context, w *watchPaths, dir string) map[string][]byte {
+	data := make(map[string][]byte)
+	for name, path := range w.files {
+		if filepath.Dir(name) != dir {
+			continue
 		}
-		err = f.watcher.Add(v)
-		if !strings.HasSuffix(v, "/") {
-			if os.IsNotExist(err) {
-				v, _ = filepath.Split(v)
-				err = f.watcher.Add(v)
-			}
+		fileData, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			f.logger.Warn("file: read error", "op", "watch", "path", path, "backend", "file", "error", err)
+			continue
 		}
+		data[f.getPath(path)] = fileData
+	}
+	if path, ok := w.dirs[dir]; ok {
+		files, err := f.Load(ctx, path)
 		if err != nil {
-			if os.IsNotExist(err) && strings.HasSuffix(v, "/") {
-				err = os.MkdirAll(v, os.FileMode(0700))
-				if err == nil {
-					err = f.watcher.Add(v)
-				}
+			f.logger.Warn("file: read dir error", "op", "watch", "path", path, "backend", "file", "error", err)
+			return data
+		}
+		for k := range w.known[dir] {
+			if _, ok := files[k]; !ok {
+				data[f.getPath(k)] = nil
 			}
 		}
-
-		if err != nil {
-			return fmt.Errorf("fsnotify.Add %s error for %s", v, err)
+		w.setKnown(dir, files)
+		for k, v := range files {
+			data[f.getPath(k)] = v
 		}
 	}
-	go f.watch(onChange)
-	return err
+	return data
 }
 
-func (f *File) watch(onChange func(map[string][]byte)) {
+func (f *File) watch(ctx context.Context, watcher *fsnotify.Watcher, w *watchPaths, onChange func(map[string][]byte), onStatus func(connected bool, err error)) {
 	var err error
 	defer func() {
-		err = f.watcher.Close()
+		err = watcher.Close()
 		if err != nil {
-			warnLog("fsnotify.Close", err.Error())
+			f.logger.Warn("file: close fsnotify error", "op", "watch", "backend", "file", "error", err)
 		}
 	}()
 	for {
 		select {
-		case event, ok := <-f.watcher.Events:
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
@@ -179,30 +560,39 @@ func (f *File) watch(onChange func(map[string][]byte)) {
 				continue
 			}
 			_, filename := filepath.Split(event.Name)
+			if filename == dataDir && event.Has(fsnotify.Create) {
+				if data := f.swapData(ctx, w, filepath.Dir(filepath.Clean(event.Name))); len(data) > 0 {
+					onChange(data)
+				}
+				continue
+			}
 			if strings.HasPrefix(filename, ".") {
 				continue
 			}
-			path := event.Name
+			path, ok := w.path(event.Name)
+			if !ok {
+				continue
+			}
 			fileData, err := ioutil.ReadFile(path)
 			if err != nil && !os.IsNotExist(err) {
-				warnLog("read file " + path, err.Error())
+				f.logger.Warn("file: read error", "op", "watch", "path", path, "backend", "file", "error", err)
 			}
 			data := make(map[string][]byte)
 			filePath := f.getPath(path)
 			data[filePath] = fileData
 			onChange(data)
 
-		case err, ok := <-f.watcher.Errors:
+		case err, ok := <-watcher.Errors:
 			if !ok {
 				return
 			}
 			if err != nil {
-				warnLog("fsnotify.Watcher", err.Error())
+				f.logger.Warn("file: fsnotify error", "op", "watch", "backend", "file", "error", err)
+				if onStatus != nil {
+					onStatus(false, err)
+					onStatus(true, nil)
+				}
 			}
 		}
 	}
 }
-
-func warnLog(action, msg string)  {
-	fmt.Printf(`{"level":"warn","time":"%s","msg":"%s","action":"%s"}`, time.Now().UTC().Format(time.RFC3339), msg, action)
-}
\ No newline at end of file
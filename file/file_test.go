@@ -0,0 +1,125 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestFile(t *testing.T) (*File, string) {
+	dir := t.TempDir()
+	f, err := New(context.Background(), &url.URL{Scheme: "file", Path: dir + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, dir
+}
+
+func writeTestFile(t *testing.T, path, data string, mode os.FileMode) {
+	if err := ioutil.WriteFile(path, []byte(data), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveTx(t *testing.T) {
+	f, dir := newTestFile(t)
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	writeTestFile(t, a, "old a", 0644)
+	revision, err := readRevision(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions := map[string]string{a: revision, b: ""}
+	newRevisions, err := f.SaveTx(context.Background(), map[string][]byte{a: nil, b: []byte("new b")}, revisions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(a); !os.IsNotExist(err) {
+		t.Fatalf("a should be removed, got %v", err)
+	}
+	if data, err := ioutil.ReadFile(b); err != nil || string(data) != "new b" {
+		t.Fatalf("b is %q, %v, want the new data", data, err)
+	}
+	if _, ok := newRevisions[b]; !ok || len(newRevisions) != 1 {
+		t.Fatalf("got revisions %v, want the revision of b", newRevisions)
+	}
+	// the old revisions conflict with the saved files
+	_, err = f.SaveTx(context.Background(), map[string][]byte{b: []byte("newer b")}, revisions)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || len(conflict.Paths) != 1 || conflict.Paths[0] != b {
+		t.Fatalf("got error %v, want the conflict of b", err)
+	}
+}
+
+func TestSaveKeepsModeAndSymlink(t *testing.T) {
+	f, dir := newTestFile(t)
+	real, link := filepath.Join(dir, "real.json"), filepath.Join(dir, "link.json")
+	writeTestFile(t, real, "old", 0600)
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(context.Background(), link, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("link is not a symlink any more, %v", err)
+	}
+	if data, err := ioutil.ReadFile(real); err != nil || string(data) != "new" {
+		t.Fatalf("real is %q, %v, want the new data", data, err)
+	}
+	if info, err := os.Stat(real); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Fatalf("the mode of real is %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestSaveTxRollback(t *testing.T) {
+	f, dir := newTestFile(t)
+	a, b, c := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json"), filepath.Join(dir, "c.json")
+	writeTestFile(t, a, "old a", 0600)
+	writeTestFile(t, c, "old c", 0644)
+	// the paths are renamed in order, so a and b are restored after renaming c fails
+	f.rename = func(oldPath, newPath string) error {
+		if filepath.Base(newPath) == "c.json" {
+			return errors.New("rename failed")
+		}
+		return os.Rename(oldPath, newPath)
+	}
+	_, err := f.SaveTx(context.Background(), map[string][]byte{a: []byte("new a"), b: []byte("new b"), c: []byte("new c")}, nil)
+	if err == nil {
+		t.Fatal("SaveTx should fail")
+	}
+	if data, err := ioutil.ReadFile(a); err != nil || string(data) != "old a" {
+		t.Fatalf("a is %q, %v, want the old data", data, err)
+	}
+	if info, err := os.Stat(a); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Fatalf("the mode of a is %v, want 0600", info.Mode().Perm())
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Fatalf("b should be removed, got %v", err)
+	}
+	if data, err := ioutil.ReadFile(c); err != nil || string(data) != "old c" {
+		t.Fatalf("c is %q, %v, want the old data", data, err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		var names []string
+		for _, v := range files {
+			names = append(names, v.Name())
+		}
+		t.Fatalf("the temp files are left, got %v", names)
+	}
+}
@@ -0,0 +1,64 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Logger the structured logger of the file client and the binder, args are key value pairs,
+// *slog.Logger implements it
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// NewJSONLogger the default logger writes the json lines of info and above to w
+func NewJSONLogger(w io.Writer) Logger {
+	return &jsonLogger{w: w}
+}
+
+type jsonLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *jsonLogger) Debug(string, ...interface{}) {}
+
+func (l *jsonLogger) Info(msg string, args ...interface{}) {
+	l.log("info", msg, args)
+}
+
+func (l *jsonLogger) Warn(msg string, args ...interface{}) {
+	l.log("warn", msg, args)
+}
+
+func (l *jsonLogger) Error(msg string, args ...interface{}) {
+	l.log("error", msg, args)
+}
+
+func (l *jsonLogger) log(level, msg string, args []interface{}) {
+	entry := map[string]interface{}{
+		"level": level,
+		"time":  time.Now().UTC().Format(time.RFC3339),
+		"msg":   msg,
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		value := args[i+1]
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		entry[fmt.Sprint(args[i])] = value
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"level": level, "msg": msg, "error": err.Error()})
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.w.Write(append(data, '\n'))
+}
@@ -3,7 +3,6 @@ package objectbind
 import (
 	"context"
 	"errors"
-	"fmt"
 	"path/filepath"
 	"reflect"
 	"sort"
@@ -11,12 +10,106 @@ import (
 	"strings"
 )
 
-func (b *Binder) saveJSONFile(ctx context.Context, path string, data []byte) (err error) {
-	data, err = b.json2Codec(path, data)
+// loadBackend load the data from the backend and keep the revisions of the versioned backend
+func (b *Binder) loadBackend(ctx context.Context, path string) (map[string][]byte, error) {
+	vb, ok := b.backend.(VersionedBackend)
+	if !ok {
+		return b.backend.Load(ctx, path)
+	}
+	data, revisions, err := vb.LoadRevision(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if b.revisions == nil {
+		b.revisions = make(map[string]string)
+	}
+	for k := range b.revisions {
+		if k == path || (strings.HasSuffix(path, "/") && strings.HasPrefix(k, path)) {
+			delete(b.revisions, k)
+		}
+	}
+	for k, v := range revisions {
+		b.revisions[k] = v
+	}
+	return data, nil
+}
+
+// saveBackend save the data to the backend, the versioned backend saves it only if the revision is not changed
+func (b *Binder) saveBackend(ctx context.Context, path string, data []byte) error {
+	vb, ok := b.backend.(VersionedBackend)
+	if !ok {
+		return b.backend.Save(ctx, path, data)
+	}
+	revision, err := vb.SaveRevision(ctx, path, data, b.revisions[path])
+	if err != nil {
+		if keys, ok := conflictKeys(err); ok {
+			return &ErrConflict{Keys: keys}
+		}
+		return err
+	}
+	if b.revisions == nil {
+		b.revisions = make(map[string]string)
+	}
+	if revision == "" {
+		delete(b.revisions, path)
+	} else {
+		b.revisions[path] = revision
+	}
+	return nil
+}
+
+// saveTx save the data in a transaction, the revisions are compared if the backend is versioned
+func (b *Binder) saveTx(ctx context.Context, tx TxBackend, data map[string][]byte) error {
+	var revisions map[string]string
+	if _, ok := b.backend.(VersionedBackend); ok {
+		revisions = make(map[string]string, len(data))
+		for path := range data {
+			revisions[path] = b.revisions[path]
+		}
+	}
+	newRevisions, err := tx.SaveTx(ctx, data, revisions)
 	if err != nil {
-		return fmt.Errorf("objectbind.JSON2Codec %s error for %s", path, err)
+		if keys, ok := conflictKeys(err); ok {
+			return &ErrConflict{Keys: keys}
+		}
+		return err
+	}
+	if revisions == nil {
+		return nil
 	}
-	return b.backend.Save(ctx, b.getFileName(path), data)
+	if b.revisions == nil {
+		b.revisions = make(map[string]string)
+	}
+	for path := range data {
+		if revision := newRevisions[path]; revision != "" {
+			b.revisions[path] = revision
+		} else {
+			delete(b.revisions, path)
+		}
+	}
+	return nil
+}
+
+// checkRevisions check the revisions of the paths before saving them to the versioned backend
+func (b *Binder) checkRevisions(ctx context.Context, paths []string) error {
+	vb, ok := b.backend.(VersionedBackend)
+	if !ok {
+		return nil
+	}
+	var conflicts []string
+	for _, path := range paths {
+		_, revisions, err := vb.LoadRevision(ctx, path)
+		if err != nil {
+			return err
+		}
+		if revisions[path] != b.revisions[path] {
+			conflicts = append(conflicts, path)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &ErrConflict{Keys: conflicts}
+	}
+	return nil
 }
 
 func (b *Binder) loadJSONFile(ctx context.Context, path string) ([]*mapData, error) {
@@ -24,7 +117,7 @@ func (b *Binder) loadJSONFile(ctx context.Context, path string) ([]*mapData, err
 	if field == nil {
 		return nil, errors.New("can not get path not in fields - " + path)
 	}
-	files, err := b.backend.Load(ctx, b.getFileName(path))
+	files, err := b.loadBackend(ctx, b.getFileName(path))
 	if err != nil {
 		return nil, err
 	}
@@ -36,23 +129,37 @@ func (b *Binder) loadJSONFile(ctx context.Context, path string) ([]*mapData, err
 		if !ok || len(d) < 1 {
 			return nil, nil
 		}
-		jsonData, err := b.codec2JSON(path, d)
+		jsonData, state, err := b.codec2JSON(path, b.getFileName(path), d)
 		if err != nil {
-			return nil, err
+			return nil, &decodeError{Path: b.getFileName(path), Err: err}
 		}
 		return []*mapData{{
 			Key:   path,
 			Value: string(jsonData),
+			state: state,
 		},
 		}, nil
 	}
-	var keys []string
+	// the files of the same key with different extensions, the file with the extension of the uri is used
+	filenames := make([]string, 0, len(files))
 	for k := range files {
-		_, item := filepath.Split(k)
-		k = b.getName(item)
-		if k != "" {
+		filenames = append(filenames, k)
+	}
+	sort.Strings(filenames)
+	names := make(map[string]string)
+	var keys []string
+	for _, filename := range filenames {
+		name, ext := b.getName(filename)
+		if name == "" {
+			continue
+		}
+		_, k := filepath.Split(name)
+		if _, ok := names[k]; !ok {
 			keys = append(keys, k)
+		} else if ext != "" {
+			continue
 		}
+		names[k] = filename
 	}
 	if field.Kind == reflect.Slice {
 		stringIntSort(keys)
@@ -61,18 +168,22 @@ func (b *Binder) loadJSONFile(ctx context.Context, path string) ([]*mapData, err
 	}
 	var dist []*mapData
 	for _, k := range keys {
-		d, ok := files[path+b.getFileName(k)]
-		if !ok || len(d) < 1 {
-			return nil, nil
+		filename := names[k]
+		d := files[filename]
+		if len(d) < 1 {
+			// the empty file is being written, it is loaded after the writing
+			continue
 		}
-		jsonData, err := b.codec2JSON(path, d)
+		jsonData, state, err := b.codec2JSON(path, filename, d)
 		if err != nil {
-			return nil, err
+			return nil, &decodeError{Path: filename, Err: err}
 		}
-
+		_, ext := b.getName(filename)
 		dist = append(dist, &mapData{
 			Key:   path + k,
 			Value: string(jsonData),
+			Ext:   ext,
+			state: state,
 		})
 	}
 	return dist, nil
@@ -82,24 +193,35 @@ func (b *Binder) watchJSONFile(ctx context.Context, paths []string, onChange fun
 	for i, v := range paths {
 		paths[i] = b.getFileName(v)
 	}
-	return b.backend.Watch(ctx, paths, func(m map[string][]byte) {
+	onData := func(m map[string][]byte) {
 		var data []*mapData
 		for k, v := range m {
-			filename := b.getName(k)
+			filename, _ := b.getName(k)
 			if filename != "" && !strings.HasSuffix(filename, "/") {
-				jsonData, err := b.codec2JSON(filename, v)
+				// the file is removed or truncated, it has nothing to decode
+				if len(v) == 0 {
+					data = append(data, &mapData{Key: filename})
+					continue
+				}
+				jsonData, state, err := b.codec2JSON(filename, k, v)
 				if err != nil {
-					warnLog("objectbind.Watch.Load " + filename, err.Error())
+					b.logger.Warn("objectbind: decode the changed file error", "op", "watch", "path", k, "backend", b.scheme, "error", err)
+					b.emit(EventDecodeFailed, []string{k}, err)
 					continue
 				}
 				data = append(data, &mapData{
 					Key:   filename,
 					Value: string(jsonData),
+					state: state,
 				})
 			}
 		}
 		onChange(data)
-	})
+	}
+	if sw, ok := b.backend.(StatusWatcher); ok {
+		return sw.WatchStatus(ctx, paths, onData, b.onWatchStatus)
+	}
+	return b.backend.Watch(ctx, paths, onData)
 }
 
 func stringIntSort(src []string) {
@@ -118,21 +240,41 @@ func stringIntSort(src []string) {
 	}
 }
 
+// getFileName get the file name of the key, the files in the bound dirs keep their own extensions
 func (b *Binder) getFileName(key string) string {
 	if !strings.HasSuffix(key, "/") && b.withExtension {
+		if b.inDir(key) {
+			if kv, ok := b.currentFiles[key]; ok && kv.Ext != "" {
+				return key + kv.Ext
+			}
+		}
 		key += b.extension
 	}
 	return key
 }
 
-func (b *Binder) getName(filename string) string {
-	if b.withExtension {
-		ext := filepath.Ext(filename)
-		if ext == b.extension {
-			filename = filename[0 : len(filename)-b.lenExtension]
-		} else {
-			filename = ""
-		}
+// getName get the key and the extension of the file, the extension is empty when it is the extension of the uri.
+// The files in the bound dirs can have the extensions of all the registered codecs unless the codec is set by WithCodec
+func (b *Binder) getName(filename string) (string, string) {
+	if !b.withExtension {
+		return filename, ""
+	}
+	ext := filepath.Ext(filename)
+	if ext == b.extension {
+		return filename[0 : len(filename)-b.lenExtension], ""
+	}
+	if ext == "" || b.customCodec || !b.inDir(filename) {
+		return "", ""
 	}
-	return filename
+	if _, ok := LookupCodec(ext); !ok {
+		return "", ""
+	}
+	return strings.TrimSuffix(filename, ext), ext
+}
+
+// inDir check if the file is in a bound dir
+func (b *Binder) inDir(filename string) bool {
+	dir, _ := filepath.Split(filename)
+	_, ok := b.fields[dir]
+	return ok && dir != ""
 }
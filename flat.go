@@ -0,0 +1,677 @@
+package objectbind
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flatCodec the codec of the flat key value files, such as the dotenv files with the keys like A_B_C=value and
+// the java properties files with the keys like a.b.c=value. The keys are mapped to the nested fields by their json
+// names, and the values are converted by the types of the fields
+type flatCodec struct {
+	name string
+	sep  string
+	// env the keys are upper case and the map keys are lower case as the dotenv files do
+	env bool
+	// repeat the scalar slices are written as the repeated keys instead of the comma separated values
+	repeat bool
+	parse  func(data []byte) ([]flatPair, error)
+	quote  func(s string) string
+}
+
+type flatPair struct {
+	key   string
+	segs  []string
+	value string
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func newEnvCodec() *flatCodec {
+	return &flatCodec{name: "env", sep: "_", env: true, parse: parseEnv, quote: quoteEnv}
+}
+
+func newPropertiesCodec() *flatCodec {
+	return &flatCodec{name: "properties", sep: ".", parse: parseProperties, quote: quoteProperties}
+}
+
+func (c *flatCodec) String() string {
+	return c.name
+}
+
+func (c *flatCodec) Unmarshal(data []byte, v interface{}) error {
+	pairs, err := c.parse(data)
+	if err != nil {
+		return err
+	}
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return fmt.Errorf("%s unmarshal requires a pointer", c.name)
+	}
+	var value interface{}
+	for _, p := range pairs {
+		value, err = c.set(value, t.Elem(), strings.Split(p.key, c.sep), p.value)
+		if err != nil {
+			return fmt.Errorf("key %s error for %s", p.key, err)
+		}
+	}
+	if value == nil {
+		return nil
+	}
+	return decodeJSONValue(value, v)
+}
+
+// decodeJSONValue decode the json value into v
+func decodeJSONValue(value interface{}, v interface{}) error {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+// set set the raw value at the key segments into the json value node of the type t, the unknown keys are ignored
+func (c *flatCodec) set(node interface{}, t reflect.Type, segs []string, raw string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if len(segs) == 0 {
+		return c.leaf(t, raw)
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Implements(textUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+			return node, nil
+		}
+		name, ft, n := c.field(t, segs)
+		if n == 0 {
+			return node, nil
+		}
+		m, _ := node.(map[string]interface{})
+		if m == nil {
+			m = make(map[string]interface{})
+		}
+		value, err := c.set(m[name], ft, segs[n:], raw)
+		if err != nil {
+			return nil, err
+		}
+		m[name] = value
+		return m, nil
+	case reflect.Map, reflect.Interface:
+		elem := t
+		if t.Kind() == reflect.Map {
+			elem = t.Elem()
+		}
+		n := 1
+		if t.Kind() == reflect.Map && isFlatScalar(elem) {
+			// the rest of the key is the map key of the scalar values
+			n = len(segs)
+		}
+		key := strings.Join(segs[:n], c.sep)
+		if c.env {
+			key = strings.ToLower(key)
+		}
+		m, _ := node.(map[string]interface{})
+		if m == nil {
+			m = make(map[string]interface{})
+		}
+		value, err := c.set(m[key], elem, segs[n:], raw)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+		return m, nil
+	case reflect.Slice, reflect.Array:
+		index, err := strconv.Atoi(segs[0])
+		if err != nil || index < 0 {
+			return node, nil
+		}
+		a, _ := node.([]interface{})
+		for len(a) <= index {
+			a = append(a, nil)
+		}
+		value, err := c.set(a[index], t.Elem(), segs[1:], raw)
+		if err != nil {
+			return nil, err
+		}
+		a[index] = value
+		return a, nil
+	}
+	if c.repeat && len(segs) == 1 {
+		// the last one of the repeated keys of the scalar field wins
+		if _, err := strconv.Atoi(segs[0]); err == nil {
+			return c.leaf(t, raw)
+		}
+	}
+	return node, nil
+}
+
+// field find the struct field whose json name matches the most key segments
+func (c *flatCodec) field(t reflect.Type, segs []string) (string, reflect.Type, int) {
+	var name string
+	var ft reflect.Type
+	var n int
+	for _, f := range flatFields(t) {
+		for i := len(segs); i > n; i-- {
+			if strings.EqualFold(strings.Join(segs[:i], c.sep), f.name) {
+				name, ft, n = f.name, f.typ, i
+				break
+			}
+		}
+	}
+	return name, ft, n
+}
+
+// leaf convert the raw value to the json value of the type t
+func (c *flatCodec) leaf(t reflect.Type, raw string) (interface{}, error) {
+	if t == durationType {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return json.Number(strconv.FormatInt(int64(d), 10)), nil
+		}
+	}
+	if t.Kind() != reflect.String && (t.Implements(textUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType)) {
+		return raw, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", raw)
+		}
+		return b, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid integer %q", raw)
+		}
+		return json.Number(raw), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if _, err := strconv.ParseUint(raw, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		return json.Number(raw), nil
+	case reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(raw, 64); err != nil || !isJSONNumber(raw) {
+			return nil, fmt.Errorf("invalid number %q", raw)
+		}
+		return json.Number(raw), nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 || !isFlatScalar(t.Elem()) {
+			return c.jsonLeaf(raw)
+		}
+		if raw == "" {
+			return []interface{}{}, nil
+		}
+		if c.repeat {
+			// every one of the repeated keys is an element, the commas are kept in the value
+			value, err := c.leaf(t.Elem(), raw)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{value}, nil
+		}
+		var a []interface{}
+		for _, s := range strings.Split(raw, ",") {
+			value, err := c.leaf(t.Elem(), strings.TrimSpace(s))
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		return a, nil
+	case reflect.Interface:
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b, nil
+		}
+		if isJSONNumber(raw) {
+			return json.Number(raw), nil
+		}
+		return raw, nil
+	}
+	return c.jsonLeaf(raw)
+}
+
+// jsonLeaf decode the raw json value of the complex field, such as LABELS={"app":"svc"}
+func (c *flatCodec) jsonLeaf(raw string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return raw, nil
+	}
+	return value, nil
+}
+
+func (c *flatCodec) Marshal(v interface{}) ([]byte, error) {
+	pairs, err := c.pairs(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, p := range pairs {
+		buf.WriteString(p.key + "=" + p.value + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// pairs get the flat pairs of v
+func (c *flatCodec) pairs(v interface{}) ([]flatPair, error) {
+	dec, err := jsonDecoder(v)
+	if err != nil {
+		return nil, err
+	}
+	var pairs []flatPair
+	t := reflect.TypeOf(v)
+	if t == nil {
+		t = reflect.TypeOf((*interface{})(nil)).Elem()
+	}
+	if err = c.flatten(dec, t, nil, &pairs); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// flatten write the json value of the type t as the flat pairs in the order of the fields, the null values
+// and the empty objects are omitted
+func (c *flatCodec) flatten(dec *json.Decoder, t reflect.Type, segs []string, pairs *[]flatPair) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		var keys []string
+		values := make(map[string][]flatPair)
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := kt.(string)
+			ft := reflect.TypeOf((*interface{})(nil)).Elem()
+			switch t.Kind() {
+			case reflect.Struct:
+				if _, typ, n := c.field(t, []string{key}); n > 0 {
+					ft = typ
+				}
+			case reflect.Map:
+				ft = t.Elem()
+			}
+			var child []flatPair
+			if err = c.flatten(dec, ft, append(segs[:len(segs):len(segs)], key), &child); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			values[key] = child
+		}
+		if t.Kind() == reflect.Map {
+			sort.Strings(keys)
+		}
+		for _, key := range keys {
+			*pairs = append(*pairs, values[key]...)
+		}
+		_, err = dec.Token()
+		return err
+	case json.Delim('['):
+		var elem reflect.Type
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			elem = t.Elem()
+		} else {
+			elem = reflect.TypeOf((*interface{})(nil)).Elem()
+		}
+		var items []flatPair
+		scalar := true
+		for i := 0; dec.More(); i++ {
+			var child []flatPair
+			if err = c.flatten(dec, elem, append(segs[:len(segs):len(segs)], strconv.Itoa(i)), &child); err != nil {
+				return err
+			}
+			if len(child) != 1 || len(child[0].segs) != len(segs)+1 ||
+				!c.repeat && strings.ContainsAny(child[0].value, ",\"'\\ ") {
+				scalar = false
+			}
+			items = append(items, child...)
+		}
+		if _, err = dec.Token(); err != nil {
+			return err
+		}
+		if scalar && len(items) > 0 && len(segs) > 0 {
+			if c.repeat {
+				for _, item := range items {
+					*pairs = append(*pairs, flatPair{key: c.key(segs), segs: segs, value: item.value})
+				}
+				return nil
+			}
+			// the scalar slices are written as the comma separated values
+			values := make([]string, 0, len(items))
+			for _, item := range items {
+				values = append(values, item.value)
+			}
+			*pairs = append(*pairs, flatPair{key: c.key(segs), segs: segs, value: strings.Join(values, ",")})
+			return nil
+		}
+		*pairs = append(*pairs, items...)
+		return nil
+	case nil:
+		return nil
+	}
+	if len(segs) == 0 {
+		return fmt.Errorf("%s top level must be an object", c.name)
+	}
+	var value string
+	switch x := token.(type) {
+	case string:
+		value = c.quote(x)
+	case json.Number:
+		value = x.String()
+		if t == durationType {
+			if n, err := x.Int64(); err == nil {
+				value = time.Duration(n).String()
+			}
+		}
+	default:
+		value = fmt.Sprint(x)
+	}
+	*pairs = append(*pairs, flatPair{key: c.key(segs), segs: segs, value: value})
+	return nil
+}
+
+func (c *flatCodec) key(segs []string) string {
+	key := strings.Join(segs, c.sep)
+	if c.env {
+		return strings.ToUpper(key)
+	}
+	return escapeProperties(key, true)
+}
+
+type flatField struct {
+	name string
+	typ  reflect.Type
+}
+
+// flatFields get the json names and the types of the fields of t, the fields of the embedded structs are promoted
+func flatFields(t reflect.Type) []flatField {
+	var fields []flatField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, flatFields(ft)...)
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, flatField{name: name, typ: sf.Type})
+	}
+	return fields
+}
+
+func isFlatScalar(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return t.Implements(textUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType)
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		return false
+	}
+	return true
+}
+
+// parseEnv parse the dotenv data, the values can be quoted by the double quotes with the escapes or by the single
+// quotes literally, and the comments after the unquoted values are removed
+func parseEnv(data []byte) ([]flatPair, error) {
+	var pairs []flatPair
+	s := strings.ReplaceAll(string(data), "\r\n", "\n")
+	for line := 1; s != ""; line++ {
+		var l string
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			l, s = s[:i], s[i+1:]
+		} else {
+			l, s = s, ""
+		}
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		l = strings.TrimSpace(strings.TrimPrefix(l, "export "))
+		i := strings.IndexByte(l, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=value", line)
+		}
+		key, value := strings.TrimSpace(l[:i]), strings.TrimLeft(l[i+1:], " \t")
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[0]
+			// the quoted value can be in multiple lines
+			rest := value[1:]
+			for !hasEnvQuote(rest, quote) && s != "" {
+				var next string
+				if j := strings.IndexByte(s, '\n'); j >= 0 {
+					next, s = s[:j], s[j+1:]
+				} else {
+					next, s = s, ""
+				}
+				rest += "\n" + next
+				line++
+			}
+			end := envQuoteEnd(rest, quote)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unclosed quote of %s", line, key)
+			}
+			value = rest[:end]
+			if quote == '"' {
+				value = unescapeEnv(value)
+			}
+		} else if j := strings.Index(value, " #"); j >= 0 {
+			value = strings.TrimSpace(value[:j])
+		} else {
+			value = strings.TrimSpace(value)
+		}
+		pairs = append(pairs, flatPair{key: key, value: value})
+	}
+	return pairs, nil
+}
+
+func hasEnvQuote(s string, quote byte) bool {
+	return envQuoteEnd(s, quote) >= 0
+}
+
+func envQuoteEnd(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && quote == '"' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeEnv(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case '"', '\\', '$', '`':
+			sb.WriteByte(s[i])
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+func quoteEnv(s string) string {
+	if !strings.ContainsAny(s, " \t\r\n\"'#$\\`=") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// parseProperties parse the java properties data, the lines ending with a backslash are continued
+func parseProperties(data []byte) ([]flatPair, error) {
+	var pairs []flatPair
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	var logical string
+	for scanner.Scan() {
+		l := strings.TrimLeft(strings.TrimSuffix(scanner.Text(), "\r"), " \t\f")
+		if logical == "" && (l == "" || l[0] == '#' || l[0] == '!') {
+			continue
+		}
+		// the line is continued by an odd number of the trailing backslashes
+		n := len(l) - len(strings.TrimRight(l, `\`))
+		if n%2 == 1 {
+			logical += l[:len(l)-1]
+			continue
+		}
+		logical += l
+		pairs = append(pairs, splitProperty(logical))
+		logical = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if logical != "" {
+		pairs = append(pairs, splitProperty(logical))
+	}
+	return pairs, nil
+}
+
+// splitProperty split the key and the value by the first unescaped '=', ':' or white space
+func splitProperty(l string) flatPair {
+	i := 0
+	for ; i < len(l); i++ {
+		if l[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", l[i]) >= 0 {
+			break
+		}
+	}
+	if i > len(l) {
+		i = len(l)
+	}
+	key, rest := l[:i], strings.TrimLeft(l[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return flatPair{key: unescapeProperties(key), value: unescapeProperties(rest)}
+}
+
+func unescapeProperties(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					sb.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			sb.WriteByte('u')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+func quoteProperties(s string) string {
+	return escapeProperties(s, false)
+}
+
+// escapeProperties escape the key or the value of the properties file
+func escapeProperties(s string, isKey bool) string {
+	var sb strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			if isKey || i == 0 {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(r)
+		case ' ':
+			if isKey || i == 0 {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
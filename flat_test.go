@@ -0,0 +1,47 @@
+package objectbind
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnvCodec(t *testing.T) {
+	codec := newEnvCodec()
+	testCodecRoundTrip(t, codec, codecRoundTripTests)
+	testCodecUnmarshal(t, codec, []codecUnmarshalTest{
+		{
+			name: "syntax",
+			data: "# comment\nexport NAME=svc # tail\nDEBUG=1\nSERVER_HOST='$host'\nSERVER_TIMEOUT=2s\n" +
+				"SERVERS_1_HOST=\"b\\n\"\nTAGS=a, b\nLABELS_APP_NAME=\"multi\nline\"\nUNKNOWN=1\n",
+			want: codecTestConf{
+				Name: "svc", Debug: true, Server: codecTestServer{Host: "$host", Timeout: 2 * time.Second},
+				Servers: []codecTestServer{{}, {Host: "b\n"}}, Tags: []string{"a", "b"},
+				Labels: map[string]string{"app_name": "multi\nline"},
+			},
+		},
+		{name: "without equal", data: "NAME=a\nDEBUG\n", err: "line 2: expected KEY=value"},
+		{name: "empty key", data: "=a\n", err: "line 1: expected KEY=value"},
+		{name: "unclosed quote", data: "NAME=\"a\nDEBUG=1\n", err: "unclosed quote of NAME"},
+		{name: "invalid boolean", data: "DEBUG=maybe\n", err: "invalid boolean"},
+		{name: "invalid integer", data: "SERVER_PORT=80x\n", err: "invalid integer"},
+	})
+}
+
+func TestPropertiesCodec(t *testing.T) {
+	codec := newPropertiesCodec()
+	testCodecRoundTrip(t, codec, codecRoundTripTests)
+	testCodecUnmarshal(t, codec, []codecUnmarshalTest{
+		{
+			name: "syntax",
+			data: "! comment\nname : svc\nserver.port 8080\nserver.host=local\\\n  host\nlabels.app.name=\\u00e9\n" +
+				"ports=80,443\nservers.0.host=a\n",
+			want: codecTestConf{
+				Name: "svc", Server: codecTestServer{Host: "localhost", Port: 8080},
+				Labels: map[string]string{"app.name": "é"}, Ports: []int{80, 443}, Servers: []codecTestServer{{Host: "a"}},
+			},
+		},
+		{name: "invalid number", data: "ratio=NaN\n", err: "invalid number"},
+		{name: "invalid unsigned integer", data: "size=-1\n", err: "invalid unsigned integer"},
+		{name: "invalid element", data: "ports=80,http\n", err: "invalid integer"},
+	})
+}
@@ -0,0 +1,148 @@
+package objectbind
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// iniCodec the codec of the ini files, the sections such as [db] or [db.replica] are mapped to the nested fields,
+// the keys before the first section are mapped to the top level fields and the repeated keys are mapped to the slices
+type iniCodec struct {
+	flat *flatCodec
+}
+
+func newINICodec() *iniCodec {
+	return &iniCodec{flat: &flatCodec{name: "ini", sep: ".", repeat: true, quote: quoteINI}}
+}
+
+func (c *iniCodec) String() string {
+	return "ini"
+}
+
+func (c *iniCodec) Unmarshal(data []byte, v interface{}) error {
+	pairs, err := parseINI(data)
+	if err != nil {
+		return err
+	}
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return fmt.Errorf("ini unmarshal requires a pointer")
+	}
+	counts := make(map[string]int, len(pairs))
+	for _, p := range pairs {
+		counts[p.key]++
+	}
+	indexes := make(map[string]int, len(pairs))
+	var value interface{}
+	for _, p := range pairs {
+		segs := p.segs
+		if counts[p.key] > 1 {
+			segs = append(segs[:len(segs):len(segs)], strconv.Itoa(indexes[p.key]))
+			indexes[p.key]++
+		}
+		value, err = c.flat.set(value, t.Elem(), segs, p.value)
+		if err != nil {
+			return fmt.Errorf("key %s error for %s", p.key, err)
+		}
+	}
+	if value == nil {
+		return nil
+	}
+	return decodeJSONValue(value, v)
+}
+
+func (c *iniCodec) Marshal(v interface{}) ([]byte, error) {
+	pairs, err := c.flat.pairs(v)
+	if err != nil {
+		return nil, err
+	}
+	var sections []string
+	keys := make(map[string][]flatPair)
+	for _, p := range pairs {
+		section := strings.Join(p.segs[:len(p.segs)-1], ".")
+		if _, ok := keys[section]; !ok && section != "" {
+			sections = append(sections, section)
+		}
+		keys[section] = append(keys[section], p)
+	}
+	var buf bytes.Buffer
+	writeKeys := func(section string) {
+		for _, p := range keys[section] {
+			buf.WriteString(p.segs[len(p.segs)-1] + " = " + p.value + "\n")
+		}
+	}
+	writeKeys("")
+	for _, section := range sections {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("[" + section + "]\n")
+		writeKeys(section)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseINI parse the ini data, the key of every pair is the section and the key joined by a dot
+func parseINI(data []byte) ([]flatPair, error) {
+	var pairs []flatPair
+	var section []string
+	for i, l := range strings.Split(string(data), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || l[0] == ';' || l[0] == '#' {
+			continue
+		}
+		if l[0] == '[' {
+			end := strings.IndexByte(l, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unclosed section", i+1)
+			}
+			section = nil
+			for _, s := range strings.Split(l[1:end], ".") {
+				if s = strings.TrimSpace(s); s != "" {
+					section = append(section, s)
+				}
+			}
+			continue
+		}
+		sep := strings.IndexAny(l, "=:")
+		if sep <= 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		key := strings.TrimSuffix(strings.TrimSpace(l[:sep]), "[]")
+		value, err := unquoteINI(strings.TrimSpace(l[sep+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		segs := append(section[:len(section):len(section)], key)
+		pairs = append(pairs, flatPair{key: strings.Join(segs, "."), segs: segs, value: value})
+	}
+	return pairs, nil
+}
+
+// unquoteINI unquote the double quoted value, or remove the comment after the unquoted value
+func unquoteINI(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) {
+		end := envQuoteEnd(s[1:], '"')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed quote")
+		}
+		return unescapeEnv(s[1 : end+1]), nil
+	}
+	for _, comment := range []string{" ;", " #", "\t;", "\t#"} {
+		if i := strings.Index(s, comment); i >= 0 {
+			s = s[:i]
+		}
+	}
+	return strings.TrimSpace(s), nil
+}
+
+func quoteINI(s string) string {
+	if s == strings.TrimSpace(s) && !strings.ContainsAny(s, "\"\\;#\r\n") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,28 @@
+package objectbind
+
+import (
+	"testing"
+	"time"
+)
+
+func TestINICodec(t *testing.T) {
+	codec := newINICodec()
+	testCodecRoundTrip(t, codec, append(codecRoundTripTests[:len(codecRoundTripTests):len(codecRoundTripTests)],
+		codecRoundTripTest{name: "commas", conf: codecTestConf{Tags: []string{"a,b", "c"}, Name: "d,e"}}))
+	testCodecUnmarshal(t, codec, []codecUnmarshalTest{
+		{
+			name: "sections",
+			data: "; comment\nname = svc ; tail\ntags[] = a\ntags[] = \"b\"\n\n[server]\nhost: localhost # tail\n" +
+				"timeout = 2s\n[ servers . 1 ]\nhost = b\n[labels]\napp = svc\n[unknown]\nkey = value\n",
+			want: codecTestConf{Name: "svc", Tags: []string{"a", "b"}, Labels: map[string]string{"app": "svc"},
+				Server: codecTestServer{Host: "localhost", Timeout: 2 * time.Second}, Servers: []codecTestServer{{}, {Host: "b"}}},
+		},
+		{name: "comma value", data: "tags = a,b\n", want: codecTestConf{Tags: []string{"a,b"}}},
+		{name: "unclosed section", data: "name = a\n[server\nhost = b\n", err: "line 2: unclosed section"},
+		{name: "without equal", data: "[server]\nhost\n", err: "line 2: expected key = value"},
+		{name: "empty key", data: "= a\n", err: "line 1: expected key = value"},
+		{name: "unclosed quote", data: "name = \"a\n", err: "line 1: unclosed quote"},
+		{name: "invalid boolean", data: "debug = maybe\n", err: "invalid boolean"},
+		{name: "invalid repeated integer", data: "ports = 80\nports = http\n", err: "invalid integer"},
+	})
+}
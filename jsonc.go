@@ -0,0 +1,669 @@
+package objectbind
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// jsoncCodec the codec of JSON with comments and JSON5, it accepts the comments, the trailing commas, the unquoted
+// keys, the single quoted strings and the JSON5 numbers on loading, and keeps the comments of the loaded file on saving
+type jsoncCodec struct {
+	name string
+}
+
+func (j *jsoncCodec) String() string {
+	return j.name
+}
+
+func (j *jsoncCodec) Unmarshal(data []byte, v interface{}) error {
+	_, err := j.UnmarshalFile(data, v)
+	return err
+}
+
+func (j *jsoncCodec) Marshal(v interface{}) ([]byte, error) {
+	node, err := newJSONCNode(v)
+	if err != nil {
+		return nil, err
+	}
+	doc := &jsoncDoc{root: node, indent: "\t"}
+	return doc.bytes(), nil
+}
+
+// UnmarshalFile unmarshal the file and keep its syntax tree with the comments
+func (j *jsoncCodec) UnmarshalFile(data []byte, v interface{}) (interface{}, error) {
+	p := &jsoncParser{data: data}
+	doc, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if doc.root == nil {
+		return nil, nil
+	}
+	jsonData, err := json.Marshal(doc.root.value())
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(jsonData, v); err != nil {
+		return nil, err
+	}
+	doc.indent = jsoncIndent(data)
+	return doc, nil
+}
+
+// MarshalFile marshal v into the syntax tree of the loaded file, the comments of the kept keys are kept
+func (j *jsoncCodec) MarshalFile(v interface{}, state interface{}) ([]byte, error) {
+	doc, ok := state.(*jsoncDoc)
+	if !ok || doc.root == nil {
+		return j.Marshal(v)
+	}
+	node, err := newJSONCNode(v)
+	if err != nil {
+		return nil, err
+	}
+	// mergeJSONCNode rewrites the values of the old tree in place, the loaded tree is the base of the next saving
+	merged := *doc
+	merged.root = mergeJSONCNode(copyJSONCNode(doc.root), node)
+	return merged.bytes(), nil
+}
+
+const (
+	jsoncObject = iota + 1
+	jsoncArray
+	jsoncScalar
+)
+
+// jsoncDoc the syntax tree of the file
+type jsoncDoc struct {
+	head   []string
+	root   *jsoncNode
+	tail   []string
+	indent string
+}
+
+type jsoncNode struct {
+	kind    int
+	entries []*jsoncEntry
+	// tail the comments before the closing bracket
+	tail          []string
+	trailingComma bool
+	// raw the source of the scalar, and scalar the json value of it
+	raw    string
+	scalar interface{}
+}
+
+// jsoncEntry the member of the object or the element of the array with its comments
+type jsoncEntry struct {
+	key    string
+	rawKey string
+	head   []string
+	line   []string
+	value  *jsoncNode
+}
+
+func (n *jsoncNode) value() interface{} {
+	switch n.kind {
+	case jsoncObject:
+		m := make(map[string]interface{}, len(n.entries))
+		for _, e := range n.entries {
+			m[e.key] = e.value.value()
+		}
+		return m
+	case jsoncArray:
+		a := make([]interface{}, 0, len(n.entries))
+		for _, e := range n.entries {
+			a = append(a, e.value.value())
+		}
+		return a
+	}
+	return n.scalar
+}
+
+// newJSONCNode create the syntax tree of v by its json form, the null members are omitted
+func newJSONCNode(v interface{}) (*jsoncNode, error) {
+	dec, err := jsonDecoder(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONCNode(dec)
+}
+
+func decodeJSONCNode(dec *json.Decoder) (*jsoncNode, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		n := &jsoncNode{kind: jsoncObject}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := kt.(string)
+			value, err := decodeJSONCNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			if value.kind == jsoncScalar && value.scalar == nil {
+				continue
+			}
+			n.entries = append(n.entries, &jsoncEntry{key: key, rawKey: jsonString(key), value: value})
+		}
+		_, err = dec.Token()
+		return n, err
+	case json.Delim('['):
+		n := &jsoncNode{kind: jsoncArray}
+		for dec.More() {
+			value, err := decodeJSONCNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.entries = append(n.entries, &jsoncEntry{value: value})
+		}
+		_, err = dec.Token()
+		return n, err
+	}
+	return &jsoncNode{kind: jsoncScalar, raw: jsonString(t), scalar: t}, nil
+}
+
+// copyJSONCNode deep copy the node tree, the comments are not changed by merging so they are shared
+func copyJSONCNode(n *jsoncNode) *jsoncNode {
+	c := *n
+	if n.entries != nil {
+		c.entries = make([]*jsoncEntry, len(n.entries))
+		for i, e := range n.entries {
+			entry := *e
+			entry.value = copyJSONCNode(e.value)
+			c.entries[i] = &entry
+		}
+	}
+	return &c
+}
+
+// mergeJSONCNode merge the values of the new node into the old node and return the merged node
+func mergeJSONCNode(old, node *jsoncNode) *jsoncNode {
+	if old.kind != node.kind {
+		return node
+	}
+	switch old.kind {
+	case jsoncObject:
+		values := make(map[string]*jsoncEntry, len(node.entries))
+		for _, e := range node.entries {
+			values[e.key] = e
+		}
+		entries := make([]*jsoncEntry, 0, len(node.entries))
+		exist := make(map[string]bool, len(old.entries))
+		for _, e := range old.entries {
+			n, ok := values[e.key]
+			if !ok {
+				continue
+			}
+			exist[e.key] = true
+			e.value = mergeJSONCNode(e.value, n.value)
+			entries = append(entries, e)
+		}
+		for _, e := range node.entries {
+			if !exist[e.key] {
+				entries = append(entries, e)
+			}
+		}
+		old.entries = entries
+	case jsoncArray:
+		entries := make([]*jsoncEntry, 0, len(node.entries))
+		for i, e := range node.entries {
+			if i < len(old.entries) {
+				old.entries[i].value = mergeJSONCNode(old.entries[i].value, e.value)
+				entries = append(entries, old.entries[i])
+			} else {
+				entries = append(entries, e)
+			}
+		}
+		old.entries = entries
+	default:
+		if jsonString(old.scalar) != jsonString(node.scalar) {
+			return node
+		}
+	}
+	return old
+}
+
+func (d *jsoncDoc) bytes() []byte {
+	var buf bytes.Buffer
+	for _, c := range d.head {
+		buf.WriteString(c)
+		buf.WriteByte('\n')
+	}
+	d.write(&buf, d.root, 0)
+	buf.WriteByte('\n')
+	for _, c := range d.tail {
+		buf.WriteString(c)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func (d *jsoncDoc) write(buf *bytes.Buffer, n *jsoncNode, depth int) {
+	if n.kind == jsoncScalar {
+		buf.WriteString(n.raw)
+		return
+	}
+	open, closing := "{", "}"
+	if n.kind == jsoncArray {
+		open, closing = "[", "]"
+	}
+	if len(n.entries) == 0 && len(n.tail) == 0 {
+		buf.WriteString(open + closing)
+		return
+	}
+	buf.WriteString(open + "\n")
+	indent := strings.Repeat(d.indent, depth+1)
+	for i, e := range n.entries {
+		for _, c := range e.head {
+			buf.WriteString(indent + c + "\n")
+		}
+		buf.WriteString(indent)
+		if n.kind == jsoncObject {
+			buf.WriteString(e.rawKey + ": ")
+		}
+		d.write(buf, e.value, depth+1)
+		if i < len(n.entries)-1 || n.trailingComma {
+			buf.WriteByte(',')
+		}
+		for _, c := range e.line {
+			buf.WriteString(" " + c)
+		}
+		buf.WriteByte('\n')
+	}
+	for _, c := range n.tail {
+		buf.WriteString(indent + c + "\n")
+	}
+	buf.WriteString(strings.Repeat(d.indent, depth) + closing)
+}
+
+// jsoncIndent get the indent of the data by its first indented line, the default is a tab as the json codec
+func jsoncIndent(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "\t"
+}
+
+type jsoncParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *jsoncParser) errorf(format string, args ...interface{}) error {
+	line := bytes.Count(p.data[:p.pos], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *jsoncParser) parse() (*jsoncDoc, error) {
+	doc := &jsoncDoc{}
+	var err error
+	if doc.head, err = p.comments(); err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.data) {
+		return doc, nil
+	}
+	if doc.root, err = p.value(); err != nil {
+		return nil, err
+	}
+	if doc.tail, err = p.comments(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.data) {
+		return nil, p.errorf("unexpected %q after the value", p.data[p.pos])
+	}
+	return doc, nil
+}
+
+// comments skip the spaces and collect the comments
+func (p *jsoncParser) comments() ([]string, error) {
+	var comments []string
+	for {
+		p.skipSpaces(false)
+		c, ok, err := p.comment()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return comments, nil
+		}
+		comments = append(comments, c)
+	}
+}
+
+// lineComments collect the comments in the rest of the current line
+func (p *jsoncParser) lineComments() ([]string, error) {
+	var comments []string
+	for {
+		p.skipSpaces(true)
+		c, ok, err := p.comment()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return comments, nil
+		}
+		comments = append(comments, c)
+		if strings.HasPrefix(c, "//") {
+			return comments, nil
+		}
+	}
+}
+
+func (p *jsoncParser) skipSpaces(inLine bool) {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\r':
+		case '\n':
+			if inLine {
+				return
+			}
+		default:
+			return
+		}
+		p.pos++
+	}
+}
+
+func (p *jsoncParser) comment() (string, bool, error) {
+	if p.pos+1 >= len(p.data) || p.data[p.pos] != '/' {
+		return "", false, nil
+	}
+	start := p.pos
+	switch p.data[p.pos+1] {
+	case '/':
+		end := bytes.IndexByte(p.data[p.pos:], '\n')
+		if end < 0 {
+			p.pos = len(p.data)
+		} else {
+			p.pos += end
+		}
+		return strings.TrimRight(string(p.data[start:p.pos]), " \t\r"), true, nil
+	case '*':
+		end := bytes.Index(p.data[p.pos+2:], []byte("*/"))
+		if end < 0 {
+			return "", false, p.errorf("unclosed comment")
+		}
+		p.pos += end + 4
+		return string(p.data[start:p.pos]), true, nil
+	}
+	return "", false, nil
+}
+
+func (p *jsoncParser) value() (*jsoncNode, error) {
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of data")
+	}
+	switch c := p.data[p.pos]; c {
+	case '{':
+		return p.container(jsoncObject, '}')
+	case '[':
+		return p.container(jsoncArray, ']')
+	case '"', '\'':
+		start := p.pos
+		s, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		return &jsoncNode{kind: jsoncScalar, raw: string(p.data[start:p.pos]), scalar: s}, nil
+	}
+	start := p.pos
+	word := p.word()
+	if word == "" {
+		return nil, p.errorf("unexpected %q", p.data[p.pos])
+	}
+	n := &jsoncNode{kind: jsoncScalar, raw: word}
+	switch word {
+	case "true":
+		n.scalar = true
+	case "false":
+		n.scalar = false
+	case "null":
+	default:
+		number, err := jsoncNumber(word)
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf("%s", err)
+		}
+		n.scalar = number
+	}
+	return n, nil
+}
+
+func (p *jsoncParser) container(kind int, closing byte) (*jsoncNode, error) {
+	n := &jsoncNode{kind: kind}
+	p.pos++
+	comma := true
+	for {
+		head, err := p.comments()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unexpected end of data")
+		}
+		if p.data[p.pos] == closing {
+			p.pos++
+			n.tail = head
+			n.trailingComma = comma && len(n.entries) > 0
+			return n, nil
+		}
+		if !comma {
+			return nil, p.errorf("expected comma before %q", p.data[p.pos])
+		}
+		e := &jsoncEntry{head: head}
+		if kind == jsoncObject {
+			if err = p.key(e); err != nil {
+				return nil, err
+			}
+		}
+		if e.value, err = p.value(); err != nil {
+			return nil, err
+		}
+		p.skipSpaces(true)
+		comma = p.pos < len(p.data) && p.data[p.pos] == ','
+		if comma {
+			p.pos++
+		}
+		if e.line, err = p.lineComments(); err != nil {
+			return nil, err
+		}
+		if !comma {
+			// the comma can be in the following lines
+			pos := p.pos
+			comments, err := p.comments()
+			if err != nil {
+				return nil, err
+			}
+			if p.pos < len(p.data) && p.data[p.pos] == ',' {
+				p.pos++
+				comma = true
+				e.line = append(e.line, comments...)
+				line, err := p.lineComments()
+				if err != nil {
+					return nil, err
+				}
+				e.line = append(e.line, line...)
+			} else {
+				p.pos = pos
+			}
+		}
+		n.entries = append(n.entries, e)
+	}
+}
+
+func (p *jsoncParser) key(e *jsoncEntry) error {
+	start := p.pos
+	if c := p.data[p.pos]; c == '"' || c == '\'' {
+		s, err := p.string()
+		if err != nil {
+			return err
+		}
+		e.key = s
+	} else {
+		e.key = p.word()
+		if e.key == "" {
+			return p.errorf("unexpected %q, expected a key", c)
+		}
+	}
+	e.rawKey = string(p.data[start:p.pos])
+	if _, err := p.comments(); err != nil {
+		return err
+	}
+	if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+		return p.errorf("expected colon after key %s", e.rawKey)
+	}
+	p.pos++
+	_, err := p.comments()
+	return err
+}
+
+// word read the unquoted key, the literal or the number
+func (p *jsoncParser) word() string {
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '_' || c == '$' || c == '.' || c == '+' || c == '-' ||
+			'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c >= utf8.RuneSelf {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return string(p.data[start:p.pos])
+}
+
+func (p *jsoncParser) string() (string, error) {
+	quote := p.data[p.pos]
+	p.pos++
+	var sb strings.Builder
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c == '\n':
+			return "", p.errorf("unexpected newline in string")
+		case c == '\\':
+			p.pos++
+			if p.pos >= len(p.data) {
+				return "", p.errorf("unexpected end of data")
+			}
+			esc := p.data[p.pos]
+			p.pos++
+			switch esc {
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'v':
+				sb.WriteByte('\v')
+			case '0':
+				sb.WriteByte(0)
+			case '\n':
+				// the line continuation
+			case 'u':
+				r, err := p.hex4()
+				if err != nil {
+					return "", err
+				}
+				// the surrogate pair such as \ud83d\ude00 is one rune
+				if utf16.IsSurrogate(r) && bytes.HasPrefix(p.data[p.pos:], []byte(`\u`)) {
+					pos := p.pos
+					p.pos += 2
+					low, err := p.hex4()
+					if err != nil {
+						return "", err
+					}
+					if pair := utf16.DecodeRune(r, low); pair != utf8.RuneError {
+						r = pair
+					} else {
+						p.pos = pos
+					}
+				}
+				sb.WriteRune(r)
+			default:
+				sb.WriteByte(esc)
+			}
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unclosed string")
+}
+
+// hex4 read the 4 hex digits of the unicode escape
+func (p *jsoncParser) hex4() (rune, error) {
+	if p.pos+4 > len(p.data) {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	r, err := strconv.ParseUint(string(p.data[p.pos:p.pos+4]), 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	p.pos += 4
+	return rune(r), nil
+}
+
+// jsoncNumber convert the JSON5 number to the json number
+func jsoncNumber(s string) (json.Number, error) {
+	n := strings.TrimPrefix(s, "+")
+	sign := ""
+	if strings.HasPrefix(n, "-") {
+		sign, n = "-", n[1:]
+	}
+	if strings.HasPrefix(n, "0x") || strings.HasPrefix(n, "0X") {
+		i, ok := new(big.Int).SetString(n[2:], 16)
+		if !ok {
+			return "", fmt.Errorf("invalid number %s", s)
+		}
+		return json.Number(sign + i.String()), nil
+	}
+	if strings.HasPrefix(n, ".") {
+		n = "0" + n
+	}
+	n = strings.Replace(n, ".e", ".0e", 1)
+	n = strings.Replace(n, ".E", ".0E", 1)
+	n = strings.TrimSuffix(n, ".")
+	number := json.Number(sign + n)
+	if _, err := number.Float64(); err != nil || !isJSONNumber(string(number)) {
+		return "", fmt.Errorf("invalid value %s", s)
+	}
+	return number, nil
+}
+
+func isJSONNumber(s string) bool {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return false
+	}
+	_, ok := v.(json.Number)
+	var extra interface{}
+	return ok && errors.Is(dec.Decode(&extra), io.EOF)
+}
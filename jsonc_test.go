@@ -0,0 +1,84 @@
+package objectbind
+
+import (
+	"strings"
+	"testing"
+)
+
+var jsoncUnmarshalTests = []codecUnmarshalTest{
+	{
+		name: "comments and trailing commas",
+		data: "// head\n{\n  /* name */ \"name\": \"svc\", // line\n  \"ports\": [80, 443,],\n}\n// tail\n",
+		want: codecTestConf{Name: "svc", Ports: []int{80, 443}},
+	},
+	{
+		name: "json5",
+		data: "{name: 'it\\'s', ratio: .5, size: 0x10, debug: true, labels: {$a_b: 'c'}}",
+		want: codecTestConf{Name: "it's", Ratio: 0.5, Size: 16, Debug: true, Labels: map[string]string{"$a_b": "c"}},
+	},
+	{
+		name: "comma after comments",
+		data: "{\"name\": \"a\" // c\n , \"size\": 1}",
+		want: codecTestConf{Name: "a", Size: 1},
+	},
+	{
+		name: "surrogate pair",
+		data: `{"name": "\ud83d\ude00"}`,
+		want: codecTestConf{Name: "😀"},
+	},
+	{
+		name: "lone surrogate",
+		data: `{"name": "\ud83d x"}`,
+		want: codecTestConf{Name: "\ufffd x"},
+	},
+	{name: "missing comma", data: "{\n\"name\": \"a\"\n\"size\": 2}", err: "expected comma"},
+	{name: "missing colon", data: `{"name" "a"}`, err: "expected colon"},
+	{name: "unclosed object", data: `{"size": 1`, err: "unexpected end"},
+	{name: "unclosed string", data: `{"name": "a}`, err: "unclosed string"},
+	{name: "unclosed comment", data: `{"size": 1 /* c}`, err: "unclosed comment"},
+	{name: "invalid number", data: `{"ratio": 1.2.3}`, err: "invalid value"},
+	{name: "invalid escape", data: `{"name": "\u12"}`, err: "invalid unicode escape"},
+	{name: "extra value", data: `{} {}`, err: "after the value"},
+}
+
+func TestJSONCCodec(t *testing.T) {
+	for _, name := range []string{"jsonc", "json5"} {
+		codec := &jsoncCodec{name: name}
+		t.Run(name, func(t *testing.T) {
+			testCodecRoundTrip(t, codec, codecRoundTripTests)
+			testCodecUnmarshal(t, codec, jsoncUnmarshalTests)
+		})
+	}
+}
+
+func TestJSONCMarshalFile(t *testing.T) {
+	codec := &jsoncCodec{name: "jsonc"}
+	src := "// head\n{\n  // about name\n  name: 'svc', // line\n  \"size\": 0x01,\n  ports: [\n    80, // http\n  ],\n}\n"
+	var conf codecTestConf
+	state, err := codec.UnmarshalFile([]byte(src), &conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Ports = append(conf.Ports, 443)
+	data, err := codec.MarshalFile(conf, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"// head\n{\n  // about name\n  name: 'svc', // line\n", "  \"size\": 0x01,\n",
+		"  ports: [\n    80, // http\n    443,\n  ],\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("%q is not kept in\n%s", want, data)
+		}
+	}
+	// MarshalFile does not change the state, so the comments of a removed key come back with it
+	if _, err = codec.MarshalFile(struct{}{}, state); err != nil {
+		t.Fatal(err)
+	}
+	data, err = codec.MarshalFile(codecTestConf{Name: "new"}, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "// about name\n  name: \"new\", // line\n") {
+		t.Fatalf("the comments of name are lost in\n%s", data)
+	}
+}
@@ -0,0 +1,29 @@
+package objectbind
+
+import (
+	"context"
+	"os"
+
+	"github.com/ti/objectbind/file"
+)
+
+// Logger the structured logger of the binder and the backends, args are key value pairs,
+// *slog.Logger implements it
+type Logger = file.Logger
+
+// defaultLogger writes the json lines of info and above to stderr
+var defaultLogger = file.NewJSONLogger(os.Stderr)
+
+type loggerKey struct{}
+
+// LoggerFromContext get the logger of the binder in ctx of NewBackend, it is the default logger if ctx has none
+func LoggerFromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return l
+	}
+	return defaultLogger
+}
+
+func contextWithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
@@ -12,6 +12,10 @@ type mapData struct {
 	// the path of the file
 	Key   string `json:"key,omitempty"`
 	Value string `json:"value,omitempty"`
+	// Ext the extension of the file in the bound dir when it is not the extension of the uri
+	Ext string `json:"-"`
+	// state the state of the file kept by the FileCodec
+	state interface{}
 }
 
 type fieldKind struct {
@@ -36,6 +40,9 @@ func marshal(key string, target interface{}, tagName string) (kvs []*mapData, er
 		var mainKvs []*mapData
 		for i := 0; i < size; i++ {
 			f := src.Type().Field(i)
+			if hiddenField(&f, tagName) {
+				continue
+			}
 			jsonFiledKey := getFiledTag("json", &f)
 			fKey := getFiledTag(tagName, &f)
 			data := src.Field(i).Interface()
@@ -101,6 +108,11 @@ func getFiledTag(tagName string, f *reflect.StructField) string {
 	return fKey
 }
 
+// hiddenField the unexported fields and the unbound fields tagged json:"-" are not saved
+func hiddenField(f *reflect.StructField, tagName string) bool {
+	return f.PkgPath != "" || (f.Tag.Get("json") == "-" && f.Tag.Get(tagName) == "")
+}
+
 func kvsToJSON(kvs []*mapData) string {
 	ret := "{"
 	kvsLen := len(kvs)
@@ -187,6 +199,9 @@ func convertMainData(target interface{}, tagName string) map[string]interface{}
 		size := src.NumField()
 		for i := 0; i < size; i++ {
 			f := src.Type().Field(i)
+			if hiddenField(&f, tagName) {
+				continue
+			}
 			jsonFiledKey := getFiledTag("json", &f)
 			// if value is null, then ignore
 			if t := f.Tag.Get(tagName); t == "" {
@@ -211,7 +226,7 @@ func isEmpty(v reflect.Value) bool {
 		return false
 	}
 	dataValue := v.Interface()
-	emptyValue := newWithValue(dataValue, nil, false)
+	emptyValue, _ := newWithValue(dataValue, nil, false)
 	return reflect.DeepEqual(dataValue, emptyValue)
 }
 
@@ -13,7 +13,13 @@ type Options struct {
 	tagName          string
 	withoutExtension bool
 	withoutWatch     bool
+	snapshot         bool
 	ttl              time.Duration
+	debounce         time.Duration
+	maxStaleness     time.Duration
+	validator        func(candidate interface{}) error
+	onError          func(err error)
+	logger           Logger
 }
 
 //Option is just Option functions
@@ -67,3 +73,50 @@ func WithTTL(t time.Duration) Option {
 		o.ttl = t
 	}
 }
+
+// WithDebounce merge the changes of the backend until no change comes in d, the bound object is changed once
+// and the callbacks only receive the settled data
+func WithDebounce(d time.Duration) Option {
+	return func(o *Options) {
+		o.debounce = d
+	}
+}
+
+// WithSnapshot decode every change to a new value and publish it atomically, read it by Binder.Load,
+// the target of Bind only holds the initial value, use Binder.Update to change the data
+func WithSnapshot(s bool) Option {
+	return func(o *Options) {
+		o.snapshot = s
+	}
+}
+
+// WithValidator validate the decoded candidate before it replaces the bound object and before it is saved,
+// the candidate is a pointer of the bound type, the invalid changes of the backend are rejected
+func WithValidator(v func(candidate interface{}) error) Option {
+	return func(o *Options) {
+		o.validator = v
+	}
+}
+
+// WithErrorHandler receive the errors of loading and watching in background, include the rejected changes
+func WithErrorHandler(h func(err error)) Option {
+	return func(o *Options) {
+		o.onError = h
+	}
+}
+
+// WithLogger the logger of the binder and the backend created by the binder, default writes json lines to stderr
+func WithLogger(l Logger) Option {
+	return func(o *Options) {
+		o.logger = l
+	}
+}
+
+// WithMaxStaleness the binder is not healthy in Status if no successful loading or heartbeat of the watch happened in d,
+// the connected watches of the backends without heartbeats such as file are always fresh, so without watching use it
+// with WithTTL
+func WithMaxStaleness(d time.Duration) Option {
+	return func(o *Options) {
+		o.maxStaleness = d
+	}
+}
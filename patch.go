@@ -0,0 +1,284 @@
+package objectbind
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchKind the kind of the patch of Binder.Patch
+type PatchKind int
+
+const (
+	// MergePatch RFC 7386 JSON Merge Patch
+	MergePatch PatchKind = iota + 1
+	// JSONPatch RFC 6902 JSON Patch
+	JSONPatch
+)
+
+// Patch apply the patch to the json form of the bound object and save it by Update, so it is validated and only the
+// changed files are saved. The patched fields must be known by the bound object
+func (b *Binder) Patch(ctx context.Context, patch []byte, kind PatchKind) error {
+	var apply func(doc interface{}) (interface{}, error)
+	switch kind {
+	case MergePatch:
+		var p interface{}
+		if err := json.Unmarshal(patch, &p); err != nil {
+			return fmt.Errorf("unmarshal merge patch error for %s", err)
+		}
+		apply = func(doc interface{}) (interface{}, error) {
+			return mergePatch(doc, p), nil
+		}
+	case JSONPatch:
+		var p Patch
+		if err := json.Unmarshal(patch, &p); err != nil {
+			return fmt.Errorf("unmarshal json patch error for %s", err)
+		}
+		apply = p.apply
+	default:
+		return fmt.Errorf("patch kind %d is not supported", kind)
+	}
+	return b.Update(ctx, func(target interface{}) error {
+		doc, err := jsonValue(target)
+		if err != nil {
+			return err
+		}
+		if doc, err = apply(doc); err != nil {
+			return fmt.Errorf("apply patch error for %s", err)
+		}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if err = decodeJSONFields(target, data, true); err != nil {
+			return fmt.Errorf("decode patched data error for %s", err)
+		}
+		return nil
+	})
+}
+
+// mergePatch apply the RFC 7386 merge patch to doc
+func mergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	d, ok := doc.(map[string]interface{})
+	if !ok {
+		d = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(d, k)
+			continue
+		}
+		d[k] = mergePatch(d[k], v)
+	}
+	return d
+}
+
+// apply apply the RFC 6902 operations to doc in order, the changed doc is returned
+func (p Patch) apply(doc interface{}) (interface{}, error) {
+	var err error
+	for _, op := range p {
+		if doc, err = op.apply(doc); err != nil {
+			return nil, fmt.Errorf("%s %s error for %s", op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func (op PatchOp) apply(doc interface{}) (interface{}, error) {
+	tokens, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case PatchAdd:
+		return addValue(doc, tokens, op.Value)
+	case PatchRemove:
+		doc, _, err = removeValue(doc, tokens)
+		return doc, err
+	case PatchReplace:
+		if _, err = getValue(doc, tokens); err != nil {
+			return nil, err
+		}
+		if doc, _, err = removeValue(doc, tokens); err != nil {
+			return nil, err
+		}
+		return addValue(doc, tokens, op.Value)
+	case PatchMove, PatchCopy:
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if op.Op == PatchMove {
+			if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return nil, errors.New("can not move a value into its child")
+			}
+			if doc, v, err = removeValue(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			if v, err = getValue(doc, from); err != nil {
+				return nil, err
+			}
+			// the copied value must not share the maps and slices with the source
+			if v, err = jsonValue(v); err != nil {
+				return nil, err
+			}
+		}
+		return addValue(doc, tokens, v)
+	case PatchTest:
+		v, err := getValue(doc, tokens)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(v, op.Value) {
+			return nil, fmt.Errorf("the value is %s, not %s", jsonString(v), jsonString(op.Value))
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("the operation %s is not supported", op.Op)
+}
+
+// parsePointer parse the RFC 6901 json pointer
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("json pointer %s does not start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func getValue(doc interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			v, ok := d[t]
+			if !ok {
+				return nil, fmt.Errorf("%s is not found", t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(d)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = d[i]
+		default:
+			return nil, fmt.Errorf("%s is not found", t)
+		}
+	}
+	return doc, nil
+}
+
+// addValue add the value to the object member or insert it to the array index, "-" appends it to the array
+func addValue(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return updateParent(doc, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch d := parent.(type) {
+		case map[string]interface{}:
+			d[key] = value
+			return d, nil
+		case []interface{}:
+			i := len(d)
+			if key != "-" {
+				var err error
+				if i, err = arrayIndex(key, len(d)); err != nil {
+					return nil, err
+				}
+			}
+			d = append(d, nil)
+			copy(d[i+1:], d[i:])
+			d[i] = value
+			return d, nil
+		}
+		return nil, fmt.Errorf("%s is not found", key)
+	})
+}
+
+func removeValue(doc interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	var removed interface{}
+	doc, err := updateParent(doc, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch d := parent.(type) {
+		case map[string]interface{}:
+			v, ok := d[key]
+			if !ok {
+				return nil, fmt.Errorf("%s is not found", key)
+			}
+			removed = v
+			delete(d, key)
+			return d, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(d)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = d[i]
+			return append(d[:i], d[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%s is not found", key)
+	})
+	return doc, removed, err
+}
+
+// updateParent run fn on the parent of the last token, and replace the parent by the result of fn
+func updateParent(doc interface{}, tokens []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		child, ok := d[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("%s is not found", tokens[0])
+		}
+		child, err := updateParent(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		d[tokens[0]] = child
+		return d, nil
+	case []interface{}:
+		i, err := arrayIndex(tokens[0], len(d)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := updateParent(d[i], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = child
+		return d, nil
+	}
+	return nil, fmt.Errorf("%s is not found", tokens[0])
+}
+
+// arrayIndex parse the array index which is not greater than max
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%s is not an array index", token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("index %d is out of range", i)
+	}
+	return i, nil
+}
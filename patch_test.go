@@ -0,0 +1,168 @@
+package objectbind
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ti/objectbind/file"
+)
+
+func TestPatchApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   string
+	}{
+		{name: "add member", doc: `{"a":1}`, patch: `[{"op":"add","path":"/b","value":2}]`, want: `{"a":1,"b":2}`},
+		{name: "add index", doc: `{"a":[1,3]}`, patch: `[{"op":"add","path":"/a/1","value":2}]`, want: `{"a":[1,2,3]}`},
+		{name: "add end index", doc: `{"a":[1]}`, patch: `[{"op":"add","path":"/a/1","value":2}]`, want: `{"a":[1,2]}`},
+		{name: "add dash", doc: `{"a":[1]}`, patch: `[{"op":"add","path":"/a/-","value":2}]`, want: `{"a":[1,2]}`},
+		{name: "add root", doc: `{"a":1}`, patch: `[{"op":"add","path":"","value":{"b":2}}]`, want: `{"b":2}`},
+		{name: "remove member", doc: `{"a":1,"b":2}`, patch: `[{"op":"remove","path":"/a"}]`, want: `{"b":2}`},
+		{name: "remove index", doc: `{"a":[1,2,3]}`, patch: `[{"op":"remove","path":"/a/1"}]`, want: `{"a":[1,3]}`},
+		{name: "replace", doc: `{"a":{"b":1}}`, patch: `[{"op":"replace","path":"/a/b","value":"c"}]`, want: `{"a":{"b":"c"}}`},
+		{
+			name: "move", doc: `{"a":{"b":1},"c":{}}`, patch: `[{"op":"move","from":"/a/b","path":"/c/d"}]`,
+			want: `{"a":{},"c":{"d":1}}`,
+		},
+		{
+			name: "copy", doc: `{"a":{"b":1}}`, patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/d","value":2}]`,
+			want: `{"a":{"b":1},"c":{"b":1,"d":2}}`,
+		},
+		{name: "test", doc: `{"a":[1,{"b":"c"}]}`, patch: `[{"op":"test","path":"/a/1","value":{"b":"c"}}]`, want: `{"a":[1,{"b":"c"}]}`},
+		{
+			name: "escaped", doc: `{"a/b":1,"m~n":2}`, patch: `[{"op":"test","path":"/a~1b","value":1},{"op":"replace","path":"/m~0n","value":3}]`,
+			want: `{"a/b":1,"m~n":3}`,
+		},
+		{name: "escaped order", doc: `{"~1":1}`, patch: `[{"op":"remove","path":"/~01"}]`, want: `{}`},
+		{name: "failing test", doc: `{"a":1}`, patch: `[{"op":"test","path":"/a","value":2}]`, err: "test /a error for the value is 1, not 2"},
+		{name: "test missing", doc: `{"a":1}`, patch: `[{"op":"test","path":"/b","value":1}]`, err: "b is not found"},
+		{name: "replace missing", doc: `{"a":1}`, patch: `[{"op":"replace","path":"/b","value":1}]`, err: "b is not found"},
+		{name: "remove missing", doc: `{"a":{}}`, patch: `[{"op":"remove","path":"/a/b/c"}]`, err: "b is not found"},
+		{name: "add out of range", doc: `{"a":[1]}`, patch: `[{"op":"add","path":"/a/2","value":2}]`, err: "index 2 is out of range"},
+		{name: "remove out of range", doc: `{"a":[1]}`, patch: `[{"op":"remove","path":"/a/1"}]`, err: "index 1 is out of range"},
+		{name: "remove dash", doc: `{"a":[1]}`, patch: `[{"op":"remove","path":"/a/-"}]`, err: "- is not an array index"},
+		{name: "leading zero", doc: `{"a":[1,2]}`, patch: `[{"op":"replace","path":"/a/01","value":3}]`, err: "01 is not an array index"},
+		{name: "move into child", doc: `{"a":{"b":1}}`, patch: `[{"op":"move","from":"/a","path":"/a/c"}]`, err: "into its child"},
+		{name: "relative pointer", doc: `{"a":1}`, patch: `[{"op":"remove","path":"a"}]`, err: "does not start with /"},
+		{name: "unknown op", doc: `{"a":1}`, patch: `[{"op":"merge","path":"/a"}]`, err: "merge is not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc interface{}
+			if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			var p Patch
+			var got interface{}
+			err := json.Unmarshal([]byte(tt.patch), &p)
+			if err == nil {
+				got, err = p.apply(doc)
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want interface{}
+			if err = json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %s, want %s", jsonString(got), tt.want)
+			}
+		})
+	}
+}
+
+// recordBackend record the base names of the saved paths
+type recordBackend struct {
+	Backend
+	mu    sync.Mutex
+	saved []string
+}
+
+func (r *recordBackend) Save(ctx context.Context, path string, data []byte) error {
+	r.mu.Lock()
+	r.saved = append(r.saved, filepath.Base(path))
+	r.mu.Unlock()
+	return r.Backend.Save(ctx, path, data)
+}
+
+func (r *recordBackend) reset() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := r.saved
+	r.saved = nil
+	return saved
+}
+
+type patchTestConf struct {
+	Name   string            `json:"name"`
+	Rules  map[string]string `json:"rules" bind:"conf/rules"`
+	Secret string            `json:"-"`
+}
+
+func TestPatch(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a"}`, "conf/rules.json": `{"a":"1"}`})
+	backend, err := file.New(context.Background(), &url.URL{Scheme: "file", Path: filepath.Join(dir, "config.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &recordBackend{Backend: backend}
+	conf := &patchTestConf{}
+	b := bindTestDir(t, conf, dir, WithBackend(recorder))
+	conf.Secret = "secret"
+	ctx := context.Background()
+
+	err = b.Patch(ctx, []byte(`[{"op":"replace","path":"/rules/a","value":"2"},{"op":"add","path":"/rules/b~1c","value":"3"}]`), JSONPatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"a": "2", "b/c": "3"}; !reflect.DeepEqual(conf.Rules, want) {
+		t.Fatalf("got rules %v, want %v", conf.Rules, want)
+	}
+	if saved := recorder.reset(); !reflect.DeepEqual(saved, []string{"rules.json"}) {
+		t.Fatalf("got saved files %v, want only rules.json", saved)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "config.json")); err != nil || string(data) != `{"name":"a"}` {
+		t.Fatalf("config.json is %q, %v, want it unchanged", data, err)
+	}
+
+	if err = b.Patch(ctx, []byte(`{"name":"b"}`), MergePatch); err != nil {
+		t.Fatal(err)
+	}
+	if saved := recorder.reset(); conf.Name != "b" || !reflect.DeepEqual(saved, []string{"config.json"}) {
+		t.Fatalf("got name %s and saved files %v, want b and only config.json", conf.Name, saved)
+	}
+	if conf.Secret != "secret" {
+		t.Fatalf("the field json can not see is lost, got %+v", conf)
+	}
+
+	for _, patch := range []string{
+		`[{"op":"test","path":"/name","value":"a"},{"op":"replace","path":"/name","value":"c"}]`,
+		`[{"op":"add","path":"/unknown","value":1}]`,
+		`[{"op":"remove","path":"/rules/z"}]`,
+	} {
+		if err = b.Patch(ctx, []byte(patch), JSONPatch); err == nil {
+			t.Fatalf("patch %s should fail", patch)
+		}
+	}
+	if saved := recorder.reset(); conf.Name != "b" || len(conf.Rules) != 2 || len(saved) != 0 {
+		t.Fatalf("the failed patches change %+v or save %v", conf, saved)
+	}
+}
@@ -12,16 +12,7 @@ import (
 
 // clone fully copy config instance, include map
 func clone(src interface{}, forceAddr bool) interface{} {
-	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
-	dec := json.NewDecoder(&buf)
-	err := enc.Encode(src)
-	if err != nil {
-		return err
-	}
-	t := reflect.Indirect(reflect.ValueOf(src)).Type()
-	dist := reflect.New(t).Interface()
-	err = dec.Decode(dist)
+	dist, err := deepCopy(src)
 	if err != nil {
 		return err
 	}
@@ -32,10 +23,73 @@ func clone(src interface{}, forceAddr bool) interface{} {
 	return reflect.Indirect(reflect.ValueOf(dist)).Interface()
 }
 
-// newWithValue fully copy config instance, include map
-func newWithValue(src interface{}, v []byte, forceAddr bool) interface{} {
+// deepCopy fully copy src to a new pointer of the same type, the fields json can not see are copied shallowly
+// and the others are decoded from the json of src, so the maps and slices of the copy are not shared with src
+func deepCopy(src interface{}) (interface{}, error) {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+	rv := reflect.Indirect(reflect.ValueOf(src))
+	dist := reflect.New(rv.Type())
+	dist.Elem().Set(rv)
+	if err = decodeJSONFields(dist.Interface(), data, false); err != nil {
+		return nil, err
+	}
+	return dist.Interface(), nil
+}
+
+// decodeJSONFields decode the json data to the pointer target, only the fields json can see are reset before decoding,
+// the unknown fields of data are rejected in strict mode
+func decodeJSONFields(target interface{}, data []byte, strict bool) error {
+	clearJSONFields(reflect.ValueOf(target).Elem())
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(target)
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// clearJSONFields set the fields json can see to zero, the pointers to structs are reallocated rather than cleared
+// in place, so the shallow copy does not change the values of its source
+func clearJSONFields(v reflect.Value) {
+	if !v.CanSet() {
+		return
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(jsonUnmarshalerType) {
+		v.Set(reflect.Zero(v.Type()))
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if (f.PkgPath != "" && !f.Anonymous) || f.Tag.Get("json") == "-" {
+				continue
+			}
+			clearJSONFields(v.Field(i))
+		}
+	case reflect.Ptr:
+		if v.IsNil() || v.Type().Elem().Kind() != reflect.Struct {
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(v.Elem())
+		v.Set(p)
+		clearJSONFields(p.Elem())
+	default:
+		v.Set(reflect.Zero(v.Type()))
+	}
+}
+
+// newWithValue new a value of the type of src and decode the json v to it
+func newWithValue(src interface{}, v []byte, forceAddr bool) (interface{}, error) {
 	if src == nil {
-		return nil
+		return nil, nil
 	}
 	t := reflect.Indirect(reflect.ValueOf(src)).Type()
 	dist := reflect.New(t).Interface()
@@ -44,14 +98,14 @@ func newWithValue(src interface{}, v []byte, forceAddr bool) interface{} {
 		dec := json.NewDecoder(buf)
 		err := dec.Decode(dist)
 		if err != nil {
-			warnLog("objectbind.NewWithValue", err.Error())
+			return nil, err
 		}
 	}
 	rv := reflect.ValueOf(src)
 	if forceAddr || rv.Kind() == reflect.Ptr {
-		return dist
+		return dist, nil
 	}
-	return reflect.Indirect(reflect.ValueOf(dist)).Interface()
+	return reflect.Indirect(reflect.ValueOf(dist)).Interface(), nil
 }
 
 // getFieldValue get GetFieldValue
@@ -138,25 +192,120 @@ func getFieldValueReflect(src reflect.Value, orgKind reflect.Kind, paths []strin
 	return getFieldValueReflect(src, orgKind, paths, newValueIfNil)
 }
 
+// wildcard the path element matches all the entries of a map or a slice, such as Map[*] or Services.*.Endpoints
+const wildcard = "*"
+
+func hasWildcard(paths []string) bool {
+	for _, p := range paths {
+		if p == wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// getFieldEntries get the values of the entries matched by the wildcard path, they are keyed by the keys of the
+// matched entries joined by "."
+func getFieldEntries(src interface{}, path string) (map[string]interface{}, error) {
+	entries := make(map[string]interface{})
+	err := walkEntries(reflect.ValueOf(src), compile(path), "", entries)
+	return entries, err
+}
+
+func walkEntries(src reflect.Value, paths []string, key string, entries map[string]interface{}) error {
+	for len(paths) > 0 && src.Kind() == reflect.Interface && !src.IsNil() {
+		src = src.Elem()
+	}
+	i := 0
+	for i < len(paths) && paths[i] != wildcard {
+		i++
+	}
+	dist, orgKind, err := getFieldValueReflect(src, src.Kind(), paths[:i], false)
+	if err != nil || !dist.IsValid() {
+		// the entry without the field is skipped
+		return nil
+	}
+	if i == len(paths) {
+		if orgKind == reflect.Ptr {
+			entries[key] = dist.Interface()
+		} else {
+			entries[key] = reflect.Indirect(dist).Interface()
+		}
+		return nil
+	}
+	for dist.Kind() == reflect.Ptr || dist.Kind() == reflect.Interface {
+		dist = dist.Elem()
+	}
+	if key != "" {
+		key += "."
+	}
+	switch dist.Kind() {
+	case reflect.Map:
+		for _, k := range dist.MapKeys() {
+			if err = walkEntries(dist.MapIndex(k), paths[i+1:], key+fmt.Sprint(k.Interface()), entries); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for n := 0; n < dist.Len(); n++ {
+			if err = walkEntries(dist.Index(n), paths[i+1:], key+strconv.Itoa(n), entries); err != nil {
+				return err
+			}
+		}
+	case reflect.Invalid:
+	default:
+		return fmt.Errorf("%s is not a map or slice", strings.Join(paths[:i], "."))
+	}
+	return nil
+}
+
+// getFieldType get the type of the field by the path, the wildcard gets the element type of the map or slice
+func getFieldType(t reflect.Type, path string) (reflect.Type, error) {
+	for _, p := range compile(path) {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := t.FieldByName(p)
+			if !ok {
+				return nil, fmt.Errorf("path %s is in invalid", path)
+			}
+			t = f.Type
+		case reflect.Map, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		case reflect.Interface:
+			return t, nil
+		default:
+			return nil, fmt.Errorf("path %s err for %s is not supported", path, t.Kind())
+		}
+	}
+	return t, nil
+}
+
+// compile split the field path by "." and the brackets, the keys in the brackets are kept as they are, such as the
+// map keys with ".", except that "\]" and "\\" are the escaped "]" and "\"
 func compile(src string) []string {
 	var a []string
 	var p int
 	l := len(src)
-	if l == 0 {
-		return a
-	}
-	var v uint8
 	for i := 0; i < l; i++ {
-		v = src[i]
-		if v == ']' {
-			a = append(a, src[p:i])
-			p = i + 2
-			i++
-			continue
-		}
-		if v == '.' || v == '[' {
+		switch src[i] {
+		case '.':
 			a = append(a, src[p:i])
 			p = i + 1
+		case '[':
+			// the brackets following the brackets have no key before them
+			if i > 0 && src[i-1] != ']' {
+				a = append(a, src[p:i])
+			}
+			key, n := bracketKey(src[i+1:])
+			a = append(a, key)
+			i += n + 1
+			if i+1 < l && src[i+1] == '.' {
+				i++
+			}
+			p = i + 1
 		}
 	}
 	if p < l {
@@ -165,6 +314,28 @@ func compile(src string) []string {
 	return a
 }
 
+// bracketKey get the unescaped key before "]" of s and the length of the escaped key
+func bracketKey(s string) (string, int) {
+	var key []byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == ']':
+			return string(key), i
+		case c == '\\' && i+1 < len(s):
+			i++
+			key = append(key, s[i])
+		default:
+			key = append(key, c)
+		}
+	}
+	return string(key), len(s)
+}
+
+// escapeKey escape the map key to be in the brackets of the field path
+func escapeKey(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "\\", "\\\\"), "]", "\\]")
+}
+
 func getFields(root string, target interface{}, tagName string) (dist map[string]*field) {
 	kind, i := getReflectInterface(target)
 	childNullValue := getChildValueByNonPtrInterface(kind, i)
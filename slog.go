@@ -0,0 +1,10 @@
+//go:build go1.21
+
+package objectbind
+
+import "log/slog"
+
+// SlogLogger use l as the Logger of the binder, the logs have the attribute component=objectbind
+func SlogLogger(l *slog.Logger) Logger {
+	return l.With("component", "objectbind")
+}
@@ -0,0 +1,122 @@
+package objectbind
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// Status the status of the binder for the health checks
+type Status struct {
+	// LastLoaded the time of the last successful loading or applied change
+	LastLoaded time.Time
+	// LastHeartbeat the time of the last heartbeat of the watch, only the backends implementing StatusWatcher report it
+	LastHeartbeat time.Time
+	// Errors the last error of every path of the backend since the path is loaded successfully
+	Errors map[string]error
+	// Revisions the backend revisions of the current files, such as the mod revision of etcd or the mtime of the file
+	Revisions map[string]string
+	// Watching the binder watches the backend
+	Watching bool
+	// WatchConnected the watch of the backend is not broken
+	WatchConnected bool
+	// Stale no successful loading or heartbeat happened in the max staleness
+	Stale bool
+	// Healthy the watch is connected when watching and the binder is not stale
+	Healthy bool
+}
+
+// binderStatus the mutable status of the binder guarded by its mutex
+type binderStatus struct {
+	lastLoaded    time.Time
+	lastHeartbeat time.Time
+	errors        map[string]error
+	watching      bool
+	watchBroken   bool
+	maxStaleness  time.Duration
+	// heartbeats the backend reports the heartbeats of its watches, or the connected watch is always fresh
+	heartbeats bool
+	// revisions the copy of the revisions of the current files, so Status does not wait for the binder lock
+	revisions map[string]string
+}
+
+// Status get the status of the binder, it does not wait for the loading or the saving in progress
+func (b *Binder) Status() *Status {
+	b.statusMu.Lock()
+	defer b.statusMu.Unlock()
+	revisions := make(map[string]string, len(b.status.revisions))
+	for k, v := range b.status.revisions {
+		revisions[k] = v
+	}
+	s := &Status{
+		LastLoaded:     b.status.lastLoaded,
+		LastHeartbeat:  b.status.lastHeartbeat,
+		Errors:         make(map[string]error, len(b.status.errors)),
+		Revisions:      revisions,
+		Watching:       b.status.watching,
+		WatchConnected: b.status.watching && !b.status.watchBroken,
+	}
+	for k, v := range b.status.errors {
+		s.Errors[k] = v
+	}
+	// the connected watch without heartbeats gets the changes as soon as they are written, so it is never stale
+	if b.status.maxStaleness > 0 && (b.status.heartbeats || !s.WatchConnected) {
+		last := s.LastLoaded
+		if s.LastHeartbeat.After(last) {
+			last = s.LastHeartbeat
+		}
+		s.Stale = time.Since(last) > b.status.maxStaleness
+	}
+	s.Healthy = !s.Stale && (!s.Watching || s.WatchConnected)
+	return s
+}
+
+// updateRevisions copy the revisions of the current files to the status, it is called with the binder lock when
+// the current files are changed
+func (b *Binder) updateRevisions() {
+	revisions := make(map[string]string, len(b.currentFiles))
+	for k := range b.currentFiles {
+		path := b.getFileName(k)
+		if revision, ok := b.revisions[path]; ok {
+			revisions[path] = revision
+		}
+	}
+	b.statusMu.Lock()
+	b.status.revisions = revisions
+	b.statusMu.Unlock()
+}
+
+// updateStatus update the status by the event
+func (b *Binder) updateStatus(ev *Event) {
+	b.statusMu.Lock()
+	defer b.statusMu.Unlock()
+	switch ev.Type {
+	case EventLoaded:
+		b.status.lastLoaded = ev.Time
+		b.status.errors = nil
+	case EventChanged:
+		b.status.lastLoaded = ev.Time
+		for _, path := range ev.Paths {
+			delete(b.status.errors, path)
+			delete(b.status.errors, filepath.Dir(path)+"/")
+		}
+	case EventDecodeFailed, EventRejected, EventBackendError:
+		if b.status.errors == nil {
+			b.status.errors = make(map[string]error)
+		}
+		for _, path := range ev.Paths {
+			b.status.errors[path] = ev.Err
+		}
+	}
+}
+
+// setWatchStatus update the status of the watch, it returns true when the broken watch is restarted
+func (b *Binder) setWatchStatus(connected bool) (restarted bool) {
+	b.statusMu.Lock()
+	defer b.statusMu.Unlock()
+	restarted = connected && b.status.watchBroken
+	b.status.watchBroken = !connected
+	if connected {
+		b.status.lastHeartbeat = time.Now()
+	}
+	return
+}
@@ -0,0 +1,51 @@
+package objectbind
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ti/objectbind/file"
+)
+
+// heartbeatBackend the file backend which claims the heartbeats, the heartbeats are sent by the test
+type heartbeatBackend struct {
+	*file.File
+}
+
+func (h *heartbeatBackend) HeartbeatInterval() time.Duration {
+	return time.Second
+}
+
+func TestStatusStaleness(t *testing.T) {
+	newDir := func() string {
+		dir := t.TempDir()
+		writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a"}`})
+		return dir
+	}
+	watched := bindTestDir(t, &bindTestConf{}, newDir(), WithMaxStaleness(10*time.Millisecond))
+	unwatched := bindTestDir(t, &bindTestConf{}, newDir(), WithMaxStaleness(10*time.Millisecond), WithoutWatch(true))
+	dir := newDir()
+	backend, err := file.New(context.Background(), &url.URL{Scheme: "file", Path: filepath.Join(dir, "config.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	heartbeats := bindTestDir(t, &bindTestConf{}, dir, WithMaxStaleness(10*time.Millisecond), WithBackend(&heartbeatBackend{File: backend}))
+	time.Sleep(30 * time.Millisecond)
+
+	if s := watched.Status(); s.Stale || !s.Healthy {
+		t.Fatalf("the connected file watch is stale, got %+v", s)
+	}
+	if s := unwatched.Status(); !s.Stale || s.Healthy {
+		t.Fatalf("the binder without watching and loading is fresh, got %+v", s)
+	}
+	if s := heartbeats.Status(); !s.Stale {
+		t.Fatalf("the watch without the heartbeats in time is fresh, got %+v", s)
+	}
+	heartbeats.onWatchStatus(true, nil)
+	if s := heartbeats.Status(); s.Stale || !s.Healthy {
+		t.Fatalf("the watch is stale after the heartbeat, got %+v", s)
+	}
+}
@@ -0,0 +1,231 @@
+package objectbind
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ChangeKind the kind of the Change
+type ChangeKind int
+
+const (
+	// ChangeUpdated the value of the field or the entry is changed, the current value is sent as updated at first
+	ChangeUpdated ChangeKind = iota + 1
+	// ChangeAdded the entry is added, the current entries are sent as added at first
+	ChangeAdded
+	// ChangeRemoved the entry is removed, its Value is nil
+	ChangeRemoved
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeUpdated:
+		return "Updated"
+	case ChangeAdded:
+		return "Added"
+	case ChangeRemoved:
+		return "Removed"
+	}
+	return "Unknown"
+}
+
+// Change the change of the subscribed field
+type Change struct {
+	Field string
+	// Key the keys of the entry matched by the wildcards of Field joined by ".", it is empty without wildcards
+	Key      string
+	Kind     ChangeKind
+	Value    interface{}
+	PreValue interface{}
+	// Patch the patch of the whole bound object in the change which fires this Change, it is nil at first
+	Patch Patch
+}
+
+// subscribeBufferSize the buffer of the Subscribe channel, the oldest change is dropped when it is full
+const subscribeBufferSize = 16
+
+// delivery the pending call of the trigger, or of the error handler when err is not nil
+type delivery struct {
+	trigger *trigger
+	change  Change
+	err     error
+}
+
+// OnChange receive the changes of field, onValue is called with the current value at first. The callbacks are called
+// in order without holding the binder lock, so they can call the methods of the binder such as Save, except Close
+// which waits for the running callbacks. It returns the function to stop receiving the changes.
+// The field with wildcards calls onValue per changed entry, use OnEntry to get the key and the kind of the entry
+func (b *Binder) OnChange(field string, onValue func(value, preValue interface{})) (unsubscribe func(), err error) {
+	return b.addTrigger(field, func(c Change) {
+		onValue(c.Value, c.PreValue)
+	}, nil)
+}
+
+// OnEntry receive the changes of field like OnChange, the field can have wildcards such as Map[*] or
+// Services.*.Endpoints to receive the added, updated and removed entries of the maps and the slices one by one
+func (b *Binder) OnEntry(field string, onChange func(c Change)) (unsubscribe func(), err error) {
+	return b.addTrigger(field, onChange, nil)
+}
+
+// addTrigger add the trigger of field, onClose is called when the binder is closed
+func (b *Binder) addTrigger(field string, onChange func(c Change), onClose func()) (func(), error) {
+	defer b.deliver()
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if b.closed {
+		return nil, fmt.Errorf("bind field %s error for the binder is closed", field)
+	}
+	current := b.value()
+	if !b.snapshot {
+		current = clone(current, false)
+	}
+	tg := &trigger{
+		filed:    field,
+		callback: onChange,
+		onClose:  onClose,
+		wildcard: hasWildcard(compile(field)),
+	}
+	if tg.wildcard {
+		if _, err := getFieldType(reflect.TypeOf(current), field); err != nil {
+			return nil, fmt.Errorf("bind field %s error for %s", field, err)
+		}
+		entries, err := getFieldEntries(current, field)
+		if err != nil {
+			return nil, fmt.Errorf("bind field %s error for %s", field, err)
+		}
+		for _, k := range sortedKeys(entries) {
+			b.deliveries = append(b.deliveries, &delivery{trigger: tg, change: Change{Field: field, Key: k, Kind: ChangeAdded, Value: entries[k]}})
+		}
+	} else {
+		v, err := getFieldValue(current, field, true, false)
+		if err != nil {
+			return nil, fmt.Errorf("bind field %s error for %s", field, err)
+		}
+		b.deliveries = append(b.deliveries, &delivery{trigger: tg, change: Change{Field: field, Kind: ChangeUpdated, Value: v, PreValue: v}})
+	}
+	b.triggers = append(b.triggers, tg)
+	return func() {
+		b.removeTrigger(tg)
+	}, nil
+}
+
+// entryChanges get the changes of the entries matched by the wildcard field of t
+func entryChanges(t *trigger, preInstance, instance interface{}) ([]Change, error) {
+	preEntries, _ := getFieldEntries(preInstance, t.filed)
+	entries, err := getFieldEntries(instance, t.filed)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for _, k := range sortedKeys(entries) {
+		preValue, ok := preEntries[k]
+		if !ok {
+			changes = append(changes, Change{Field: t.filed, Key: k, Kind: ChangeAdded, Value: entries[k]})
+		} else if !reflect.DeepEqual(preValue, entries[k]) {
+			changes = append(changes, Change{Field: t.filed, Key: k, Kind: ChangeUpdated, Value: entries[k], PreValue: preValue})
+		}
+	}
+	for _, k := range sortedKeys(preEntries) {
+		if _, ok := entries[k]; !ok {
+			changes = append(changes, Change{Field: t.filed, Key: k, Kind: ChangeRemoved, PreValue: preEntries[k]})
+		}
+	}
+	return changes, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Subscribe receive the changes of field from the channel as OnEntry, the current value is sent at first. The oldest
+// change is dropped when the channel is full, the channel is closed by cancel or when the binder is closed
+func (b *Binder) Subscribe(field string) (<-chan Change, func(), error) {
+	ch := make(chan Change, subscribeBufferSize)
+	var mu sync.Mutex
+	var closed bool
+	closeChan := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+	unsubscribe, err := b.addTrigger(field, func(c Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		for {
+			select {
+			case ch <- c:
+				return
+			default:
+			}
+			select {
+			case <-ch:
+			default:
+			}
+		}
+	}, closeChan)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, func() {
+		unsubscribe()
+		closeChan()
+	}, nil
+}
+
+// removeTrigger remove the trigger, it is not called any more
+func (b *Binder) removeTrigger(tg *trigger) {
+	atomic.StoreInt32(&tg.removed, 1)
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	for i, t := range b.triggers {
+		if t == tg {
+			b.triggers = append(b.triggers[:i:i], b.triggers[i+1:]...)
+			return
+		}
+	}
+}
+
+// deliver call the triggers of the pending deliveries without holding the binder lock, the deliveries added by the
+// callbacks are delivered after them by the same loop
+func (b *Binder) deliver() {
+	b.locker.Lock()
+	if b.delivering {
+		b.locker.Unlock()
+		return
+	}
+	b.delivering = true
+	for len(b.deliveries) > 0 && !b.closed {
+		deliveries := b.deliveries
+		b.deliveries = nil
+		// take the read lock before releasing the binder lock, so Close can not finish between the check of closed
+		// and the callbacks
+		b.deliverMu.RLock()
+		b.locker.Unlock()
+		for _, d := range deliveries {
+			if d.err != nil {
+				b.onError(d.err)
+			} else if atomic.LoadInt32(&d.trigger.removed) == 0 {
+				d.trigger.callback(d.change)
+			}
+		}
+		b.deliverMu.RUnlock()
+		b.locker.Lock()
+	}
+	b.deliveries = nil
+	b.delivering = false
+	b.locker.Unlock()
+}
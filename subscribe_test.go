@@ -0,0 +1,256 @@
+package objectbind
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bindSubscribeTest bind the config with the name a without watching, the changes are made by Update
+func bindSubscribeTest(t *testing.T) *Binder {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a"}`})
+	return bindTestDir(t, &bindTestConf{}, dir, WithoutWatch(true))
+}
+
+func setName(t *testing.T, b *Binder, name string) {
+	err := b.Update(context.Background(), func(target interface{}) error {
+		target.(*bindTestConf).Name = name
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOnChangeOrder(t *testing.T) {
+	b := bindSubscribeTest(t)
+	var mu sync.Mutex
+	var calls []string
+	for _, id := range []string{"1", "2"} {
+		id := id
+		if _, err := b.OnChange("Name", func(value, preValue interface{}) {
+			mu.Lock()
+			calls = append(calls, fmt.Sprintf("%s:%v->%v", id, preValue, value))
+			mu.Unlock()
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setName(t, b, "b")
+	setName(t, b, "c")
+	want := []string{"1:a->a", "2:a->a", "1:a->b", "2:a->b", "1:b->c", "2:b->c"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Fatalf("got calls %v, want %v", calls, want)
+	}
+}
+
+func TestOnChangeOrderConcurrent(t *testing.T) {
+	b := bindSubscribeTest(t)
+	if err := b.Update(context.Background(), func(target interface{}) error {
+		target.(*bindTestConf).Name = "0"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var values []string
+	if _, err := b.OnChange("Name", func(value, preValue interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		// every change follows the change before it
+		if n := len(values); n > 0 && values[n-1] != preValue {
+			t.Errorf("got the change %v->%v after %v", preValue, value, values[n-1])
+		}
+		values = append(values, value.(string))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				err := b.Update(context.Background(), func(target interface{}) error {
+					c := target.(*bindTestConf)
+					n, err := strconv.Atoi(c.Name)
+					c.Name = strconv.Itoa(n + 1)
+					return err
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(values) != 81 || values[80] != "80" {
+		t.Fatalf("got %d changes ending with %s, want 81 changes ending with 80", len(values), values[len(values)-1])
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	b := bindSubscribeTest(t)
+	var mu sync.Mutex
+	var values, selfValues []interface{}
+	unsubscribe, err := b.OnChange("Name", func(value, preValue interface{}) {
+		mu.Lock()
+		values = append(values, value)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var self func()
+	self, err = b.OnChange("Name", func(value, preValue interface{}) {
+		mu.Lock()
+		selfValues = append(selfValues, value)
+		mu.Unlock()
+		if value == "b" {
+			// unsubscribe itself in the callback
+			self()
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setName(t, b, "b")
+	unsubscribe()
+	unsubscribe()
+	setName(t, b, "c")
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(values) != "[a b]" || fmt.Sprint(selfValues) != "[a b]" {
+		t.Fatalf("got values %v and %v, want [a b] before unsubscribing", values, selfValues)
+	}
+}
+
+func TestOnChangeInCallback(t *testing.T) {
+	b := bindSubscribeTest(t)
+	var mu sync.Mutex
+	var inner []interface{}
+	var once sync.Once
+	if _, err := b.OnChange("Name", func(value, preValue interface{}) {
+		if value != "b" {
+			return
+		}
+		once.Do(func() {
+			// the new callback gets the current value after this callback returns
+			if _, err := b.OnChange("Name", func(value, preValue interface{}) {
+				mu.Lock()
+				inner = append(inner, value)
+				mu.Unlock()
+			}); err != nil {
+				t.Error(err)
+			}
+		})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		setName(t, b, "b")
+		setName(t, b, "c")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnChange in the callback is blocked")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(inner) != "[b c]" {
+		t.Fatalf("got %v, want [b c]", inner)
+	}
+}
+
+func TestSubscribeDropOldest(t *testing.T) {
+	b := bindSubscribeTest(t)
+	ch, cancel, err := b.Subscribe("Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the current value a and the 20 changes, the 5 oldest are dropped
+	for i := 1; i <= 20; i++ {
+		setName(t, b, strconv.Itoa(i))
+	}
+	for i := 5; i <= 20; i++ {
+		select {
+		case c := <-ch:
+			if c.Value != strconv.Itoa(i) {
+				t.Fatalf("got %v, want %d", c.Value, i)
+			}
+		default:
+			t.Fatalf("the change %d is dropped", i)
+		}
+	}
+	cancel()
+	if c, ok := <-ch; ok {
+		t.Fatalf("got %v after cancel, want the channel closed", c.Value)
+	}
+	cancel()
+}
+
+func TestCloseWaitsForCallback(t *testing.T) {
+	b := bindSubscribeTest(t)
+	ch, _, err := b.Subscribe("Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var values []interface{}
+	started, release := make(chan struct{}), make(chan struct{})
+	if _, err = b.OnChange("Name", func(value, preValue interface{}) {
+		mu.Lock()
+		values = append(values, value)
+		mu.Unlock()
+		if value == "b" {
+			close(started)
+			<-release
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	updated := make(chan struct{})
+	go func() {
+		defer close(updated)
+		setName(t, b, "b")
+	}()
+	<-started
+	closed := make(chan error)
+	go func() {
+		closed <- b.Close(context.Background())
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returns before the running callback")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err = <-closed; err != nil {
+		t.Fatal(err)
+	}
+	<-updated
+	// the changes after Close are not delivered
+	_ = b.Update(context.Background(), func(target interface{}) error {
+		target.(*bindTestConf).Name = "c"
+		return nil
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(values) != "[a b]" {
+		t.Fatalf("got %v, want [a b] before Close", values)
+	}
+	for c := range ch {
+		if c.Value == "c" {
+			t.Fatal("the channel gets the change after Close")
+		}
+	}
+}
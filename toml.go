@@ -0,0 +1,65 @@
+package objectbind
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tomlCodec the toml codec of the json form of the values. TOML has no null, so the null values are omitted, and the
+// top level must be a table
+type tomlCodec struct{}
+
+func (t *tomlCodec) String() string {
+	return "toml"
+}
+
+func (t *tomlCodec) Unmarshal(data []byte, v interface{}) error {
+	var m map[string]interface{}
+	if _, err := toml.Decode(string(data), &m); err != nil {
+		return err
+	}
+	jsonData, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+func (t *tomlCodec) Marshal(v interface{}) ([]byte, error) {
+	value, err := jsonForm(v, tomlValue)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return nil, errors.New("toml top level must be a table")
+	}
+	var buf bytes.Buffer
+	if err = toml.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// tomlValue convert the json leaf to the toml value, the json numbers are converted to the integers or the floats and
+// the integers out of the range of int64 are rejected, the arrays can not contain null
+func tomlValue(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case nil:
+		return nil, errors.New("toml array can not contain null")
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i, nil
+		}
+		// the toml integers are 64-bit signed, the greater unsigned integers can not be kept as the floats
+		if !strings.ContainsAny(x.String(), ".eE") {
+			return nil, fmt.Errorf("toml integer %s is out of range", x)
+		}
+		return x.Float64()
+	}
+	return v, nil
+}
@@ -0,0 +1,37 @@
+package objectbind
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTOMLCodec(t *testing.T) {
+	codec := &tomlCodec{}
+	testCodecRoundTrip(t, codec, codecRoundTripTests)
+	testCodecUnmarshal(t, codec, []codecUnmarshalTest{
+		{
+			name: "tables",
+			data: "# comment\nname = \"svc\"\nports = [80, 443]\n[server]\nhost = 'localhost'\n[[servers]]\nhost = \"a\"\n",
+			want: codecTestConf{Name: "svc", Ports: []int{80, 443}, Server: codecTestServer{Host: "localhost"},
+				Servers: []codecTestServer{{Host: "a"}}},
+		},
+		{name: "unclosed table", data: "[server\nhost = \"a\"\n", err: "toml"},
+		{name: "without value", data: "name =\n", err: "toml"},
+	})
+}
+
+func TestTOMLMarshalRange(t *testing.T) {
+	codec := &tomlCodec{}
+	data, err := codec.Marshal(codecTestConf{Size: math.MaxInt64})
+	if err != nil || !strings.Contains(string(data), "size = 9223372036854775807") {
+		t.Fatalf("got %q, %v, want the max int64", data, err)
+	}
+	_, err = codec.Marshal(codecTestConf{Size: math.MaxInt64 + 1})
+	if err == nil || !strings.Contains(err.Error(), "toml integer 9223372036854775808 is out of range") {
+		t.Fatalf("got error %v, want the out of range error", err)
+	}
+	if _, err = codec.Marshal([]int{1}); err == nil {
+		t.Fatal("the top level array should be rejected")
+	}
+}
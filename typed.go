@@ -0,0 +1,77 @@
+package objectbind
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Typed the binder of a T value, the value is decoded in snapshot mode
+type Typed[T any] struct {
+	binder *Binder
+}
+
+// BindTyped bind a new T with the defaults to uri
+func BindTyped[T any](ctx context.Context, uri string, defaults T, opts ...Option) (*Typed[T], error) {
+	target := new(T)
+	*target = defaults
+	opts = append(opts[:len(opts):len(opts)], WithSnapshot(true))
+	binder, err := Bind(ctx, target, uri, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Typed[T]{binder: binder}, nil
+}
+
+// Get get the current value, the maps and slices in it are shared with other readers and must not be changed
+func (t *Typed[T]) Get() T {
+	return *t.binder.Load().(*T)
+}
+
+// Update change a copy of the current value and save it, see Binder.Update
+func (t *Typed[T]) Update(ctx context.Context, fn func(*T) error) error {
+	return t.binder.Update(ctx, func(target interface{}) error {
+		return fn(target.(*T))
+	})
+}
+
+// Binder get the underlying binder
+func (t *Typed[T]) Binder() *Binder {
+	return t.binder
+}
+
+// Close close the underlying binder
+func (t *Typed[T]) Close(ctx context.Context) error {
+	return t.binder.Close(ctx)
+}
+
+// OnField receive the changes of the field of t, the field value must be a F, the field with wildcards receives
+// the changed entries one by one, and the removed entries are the zero F. It returns the function to stop receiving the changes
+func OnField[T, F any](t *Typed[T], field string, onValue func(value, preValue F)) (unsubscribe func(), err error) {
+	ft := reflect.TypeOf((*F)(nil)).Elem()
+	if hasWildcard(compile(field)) {
+		et, err := getFieldType(reflect.TypeOf(t.binder.Load()), field)
+		if err != nil {
+			return nil, fmt.Errorf("bind field %s error for %s", field, err)
+		}
+		if !et.AssignableTo(ft) {
+			return nil, fmt.Errorf("bind field %s error for %s is not %s", field, et, ft)
+		}
+	} else {
+		v, err := getFieldValue(t.binder.Load(), field, true, false)
+		if err != nil {
+			return nil, fmt.Errorf("bind field %s error for %s", field, err)
+		}
+		if _, ok := v.(F); !ok {
+			return nil, fmt.Errorf("bind field %s error for %T is not %s", field, v, ft)
+		}
+	}
+	return t.binder.OnChange(field, func(value, preValue interface{}) {
+		onValue(typedValue[F](value), typedValue[F](preValue))
+	})
+}
+
+func typedValue[F any](v interface{}) F {
+	f, _ := v.(F)
+	return f
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 )
 
 // watch save the data
@@ -15,71 +17,196 @@ func (b *Binder) watch(ctx context.Context) error {
 		watchFiles = append(watchFiles, v.Path)
 	}
 	return b.watchJSONFile(ctx, watchFiles, func(kvs []*mapData) {
-		b.locker.Lock()
-		defer b.locker.Unlock()
-		loadedPaths := make(map[string]bool)
-		var dataFiles []*mapData
-		var changedPaths []string
-		for _, kv := range kvs {
-			currentKV, ok := b.currentFiles[kv.Key]
-			if ok && currentKV.Value == kv.Value {
-				continue
-			}
-			field, ok := b.fields[kv.Key]
-			if !ok {
-				field, ok = b.fields[filepath.Dir(kv.Key)+"/"]
-			}
-			if !ok || loadedPaths[field.Path] {
-				continue
-			}
-			if !strings.HasSuffix(field.Path, "/") {
-				dataFiles = append(dataFiles, kv)
-				loadedPaths[kv.Key] = true
-				continue
-			}
-			changedFiles, err := b.loadJSONFile(ctx, field.Path)
-			if err != nil {
-				warnLog("objectbind.LoadFile " + field.Path, err.Error())
-				return
-			}
-			dataFiles = append(dataFiles, changedFiles...)
-			loadedPaths[field.Path] = true
-			changedPaths = append(changedPaths, field.Path)
+		if b.debounce > 0 {
+			b.debounceChanges(ctx, kvs)
+			return
 		}
-		if len(dataFiles) == 0 {
+		b.onChange(ctx, kvs)
+	})
+}
+
+// onWatchStatus report the broken watch and the heartbeat, and reload all the files when the watch is restarted
+func (b *Binder) onWatchStatus(connected bool, err error) {
+	if err != nil {
+		b.logger.Warn("objectbind: watch error", "op", "watch", "path", b.root, "backend", b.scheme, "error", err)
+		b.emit(EventBackendError, []string{b.getFileName(b.root)}, err)
+	}
+	restarted := b.setWatchStatus(connected)
+	b.locker.Lock()
+	closed := b.closed
+	b.locker.Unlock()
+	if !restarted || closed {
+		return
+	}
+	b.emit(EventWatchRestarted, []string{b.getFileName(b.root)}, nil)
+	if err := b.ForceLoad(b.ctx); err != nil && b.ctx.Err() == nil {
+		b.reportError("reload", b.root, err)
+	}
+}
+
+// debounceChanges merge the changes until no change comes in the debounce duration, then apply them at once
+func (b *Binder) debounceChanges(ctx context.Context, kvs []*mapData) {
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+	if b.pending == nil {
+		b.pending = make(map[string]*mapData)
+	}
+	for _, kv := range kvs {
+		b.pending[kv.Key] = kv
+	}
+	if b.debounceTimer != nil {
+		b.debounceTimer.Stop()
+	}
+	b.debounceTimer = time.AfterFunc(b.debounce, func() {
+		b.flushMu.Lock()
+		defer b.flushMu.Unlock()
+		b.pendingMu.Lock()
+		pending := b.pending
+		b.pending = nil
+		b.pendingMu.Unlock()
+		if len(pending) == 0 {
 			return
 		}
-		err := unmarshal(b.root, dataFiles, b.instance, b.tagName)
+		keys := make([]string, 0, len(pending))
+		for k := range pending {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		changes := make([]*mapData, 0, len(keys))
+		for _, k := range keys {
+			changes = append(changes, pending[k])
+		}
+		b.onChange(ctx, changes)
+	})
+}
+
+// onChange apply the changed files, the errors and the rejected changes are reported to the error handler
+func (b *Binder) onChange(ctx context.Context, kvs []*mapData) {
+	if err := b.applyChanges(ctx, kvs); err != nil {
+		b.reportError("watch", b.root, err)
+	}
+}
+
+// applyChanges apply the changed files, the bound object is not changed if the changed files are not valid
+func (b *Binder) applyChanges(ctx context.Context, kvs []*mapData) error {
+	defer b.deliver()
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if b.closed {
+		return nil
+	}
+	loadedPaths := make(map[string]bool)
+	var dataFiles []*mapData
+	var changedPaths []string
+	for _, kv := range kvs {
+		currentKV, ok := b.currentFiles[kv.Key]
+		if ok && currentKV.Value == kv.Value {
+			continue
+		}
+		field, ok := b.fields[kv.Key]
+		if !ok {
+			field, ok = b.fields[filepath.Dir(kv.Key)+"/"]
+		}
+		if !ok || loadedPaths[field.Path] {
+			continue
+		}
+		// the removed file keeps its bound value and is saved again by the next saving, the dir is reloaded
+		if kv.Value == "" && !strings.HasSuffix(field.Path, "/") {
+			delete(b.currentFiles, kv.Key)
+			continue
+		}
+		// the versioned backend reloads the file to get its revision
+		if _, versioned := b.backend.(VersionedBackend); !versioned && !strings.HasSuffix(field.Path, "/") {
+			dataFiles = append(dataFiles, kv)
+			loadedPaths[kv.Key] = true
+			continue
+		}
+		changedFiles, err := b.loadJSONFile(ctx, field.Path)
 		if err != nil {
-			warnLog("objectbind.onChange.Unmarshal", err.Error())
-		}
-		currentFiles := b.currentFiles
-		for _, v := range changedPaths {
-			for k := range currentFiles {
-				path, _ := filepath.Split(k)
-				if v == path {
-					delete(b.currentFiles, k)
-				}
+			err = fmt.Errorf("load file %s error for %w", field.Path, err)
+			typ, paths := b.loadEvent(field.Path, err)
+			b.emit(typ, paths, err)
+			return err
+		}
+		dataFiles = append(dataFiles, changedFiles...)
+		loadedPaths[field.Path] = true
+		changedPaths = append(changedPaths, field.Path)
+	}
+	if len(dataFiles) == 0 && len(changedPaths) == 0 {
+		return nil
+	}
+	err := b.decode(dataFiles, changedPaths)
+	if err != nil {
+		b.emit(decodeEventType(err), append(b.filePaths(fileKeys(dataFiles)), changedPaths...), err)
+		return err
+	}
+	// the files of the changed dirs are replaced by the reloaded files
+	removed := make(map[string]*mapData)
+	for _, v := range changedPaths {
+		for k, kv := range b.currentFiles {
+			if path, _ := filepath.Split(k); v == path {
+				removed[k] = kv
+				delete(b.currentFiles, k)
 			}
 		}
-		for _, kv := range dataFiles {
-			b.currentFiles[kv.Key] = kv
+	}
+	var changedKeys []string
+	for _, kv := range dataFiles {
+		preKV, ok := removed[kv.Key]
+		if ok {
+			delete(removed, kv.Key)
+		} else {
+			preKV, ok = b.currentFiles[kv.Key]
+		}
+		if !ok || preKV.Value != kv.Value {
+			changedKeys = append(changedKeys, kv.Key)
 		}
-
-		b.notifyChanges(ctx)
-	})
+		b.currentFiles[kv.Key] = kv
+	}
+	for k := range removed {
+		changedKeys = append(changedKeys, k)
+	}
+	b.updateRevisions()
+	patch := b.notifyChanges(ctx)
+	if len(changedKeys) > 0 {
+		sort.Strings(changedKeys)
+		b.emitPatch(EventChanged, b.filePaths(changedKeys), patch)
+	}
+	return nil
 }
 
-//notifyChanges notify some trigger on data
-func (b *Binder) notifyChanges(ctx context.Context) {
-	if reflect.DeepEqual(b.instance, b.preInstance) {
-		return
+//notifyChanges notify some trigger on data, the triggers are called by deliver after the locker is unlocked.
+// It returns the patch of the change
+func (b *Binder) notifyChanges(ctx context.Context) Patch {
+	instance := b.value()
+	if reflect.DeepEqual(instance, b.preInstance) {
+		return nil
+	}
+	// the triggers are called without the locker, so they get the values of a copy unless it is a snapshot
+	current := instance
+	if !b.snapshot {
+		current = clone(instance, false)
+	}
+	patch, err := diff(b.preInstance, current)
+	if err != nil {
+		b.logger.Warn("objectbind: can not get the diff of the change", "op", "notify", "path", b.root, "error", err)
 	}
 	for _, t := range b.triggers {
+		if t.wildcard {
+			changes, err := entryChanges(t, b.preInstance, current)
+			if err != nil {
+				b.logger.Warn("objectbind: can not get the entries of the field", "op", "notify", "field", t.filed, "error", err)
+			}
+			for _, c := range changes {
+				c.Patch = patch
+				b.deliveries = append(b.deliveries, &delivery{trigger: t, change: c})
+			}
+			continue
+		}
 		oldValue, _ := getFieldValue(b.preInstance, t.filed, true, false)
-		newValue, err := getFieldValue(b.instance, t.filed, true, false)
+		newValue, err := getFieldValue(current, t.filed, true, false)
 		if err != nil {
-			warnLog("notifyChanges", fmt.Sprintf("can not get value by field %s for %s", t.filed, err))
+			b.logger.Warn("objectbind: can not get the value of the field", "op", "notify", "field", t.filed, "error", err)
 			continue
 		}
 		if newValue == nil && oldValue == nil {
@@ -102,7 +229,8 @@ func (b *Binder) notifyChanges(ctx context.Context) {
 		if reflect.DeepEqual(newValue, oldValue) {
 			continue
 		}
-		t.callback(newValue, oldValue)
+		b.deliveries = append(b.deliveries, &delivery{trigger: t, change: Change{Field: t.filed, Kind: ChangeUpdated, Value: newValue, PreValue: oldValue, Patch: patch}})
 	}
-	b.preInstance = clone(b.instance, false)
+	b.preInstance = clone(instance, false)
+	return patch
 }
@@ -0,0 +1,261 @@
+package objectbind
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type watchTestRule struct {
+	N int `json:"n"`
+}
+
+type watchTestConf struct {
+	Name  string                   `json:"name"`
+	Rules map[string]watchTestRule `json:"rules" bind:"rules/"`
+}
+
+// waitChange wait for the next change of the callbacks
+func waitChange(t *testing.T, changes <-chan Change) Change {
+	t.Helper()
+	select {
+	case c := <-changes:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change is received")
+	}
+	return Change{}
+}
+
+func TestWatchRemovedEntry(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a"}`, "rules/a.json": `{"n":1}`, "rules/b.json": `{"n":2}`})
+	b := bindTestDir(t, &watchTestConf{}, dir)
+	changes := make(chan Change, 16)
+	if _, err := b.OnEntry("Rules[*]", func(c Change) { changes <- c }); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if c := waitChange(t, changes); c.Kind != ChangeAdded {
+			t.Fatalf("got %s of %s at first, want Added", c.Kind, c.Key)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, "rules", "a.json")); err != nil {
+		t.Fatal(err)
+	}
+	c := waitChange(t, changes)
+	if c.Kind != ChangeRemoved || c.Key != "a" || c.PreValue != (watchTestRule{N: 1}) {
+		t.Fatalf("got %s of %s with %v, want the removed a", c.Kind, c.Key, c.PreValue)
+	}
+}
+
+func TestWatchRemovedFileIsNotDecodeFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a"}`, "rules/a.json": `{"n":1}`, "rules/b.json": `{"n":2}`})
+	conf := &watchTestConf{}
+	b := bindTestDir(t, conf, dir)
+	if err := os.Remove(filepath.Join(dir, "config.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "rules", "a.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(filepath.Join(dir, "rules", "b.json"), 0); err != nil {
+		t.Fatal(err)
+	}
+	// the change of c is applied after the changes before it
+	writeTestFiles(t, dir, map[string]string{"rules/c.json": `{"n":3}`})
+	for done := false; !done; {
+		select {
+		case ev := <-b.Events():
+			if ev.Type == EventDecodeFailed {
+				t.Fatalf("got %s of %v for %v", ev.Type, ev.Paths, ev.Err)
+			}
+			done = ev.Type == EventChanged && len(ev.Paths) > 0 && filepath.Base(ev.Paths[len(ev.Paths)-1]) == "c.json"
+		case <-time.After(5 * time.Second):
+			t.Fatal("the change of c is not applied")
+		}
+	}
+	if errs := b.Status().Errors; len(errs) > 0 {
+		t.Fatalf("got status errors %v", errs)
+	}
+	if conf.Name != "a" || len(conf.Rules) != 1 || conf.Rules["c"].N != 3 {
+		t.Fatalf("got %+v, want the name kept and only the rule c", conf)
+	}
+}
+
+func TestWatchDebounce(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a"}`, "rules/a.json": `{"n":1}`})
+	b := bindTestDir(t, &watchTestConf{}, dir, WithDebounce(200*time.Millisecond))
+	names := make(chan interface{}, 16)
+	if _, err := b.OnChange("Name", func(value, preValue interface{}) { names <- value }); err != nil {
+		t.Fatal(err)
+	}
+	if name := <-names; name != "a" {
+		t.Fatalf("got %v at first, want a", name)
+	}
+	// the burst of the writes is applied at once after it settles
+	for _, name := range []string{"b", "c", "d"} {
+		writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"` + name + `"}`, "rules/" + name + ".json": `{"n":2}`})
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case name := <-names:
+		if name != "d" {
+			t.Fatalf("got the intermediate name %v, want the settled d", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the changes are not applied")
+	}
+	select {
+	case name := <-names:
+		t.Fatalf("got the name %v after the settled change", name)
+	case <-time.After(300 * time.Millisecond):
+	}
+	var changed int
+	for len(b.Events()) > 0 {
+		if ev := <-b.Events(); ev.Type == EventChanged {
+			changed++
+		}
+	}
+	if changed != 1 {
+		t.Fatalf("got %d Changed events, want the burst in one", changed)
+	}
+	if rules := b.Load().(*watchTestConf).Rules; len(rules) != 4 {
+		t.Fatalf("got rules %v, want all the written rules", rules)
+	}
+}
+
+// swapDataDir write the files to a new timestamped dir of the kubernetes volume dir and swap ..data to it, the files
+// of the volume are the symlinks to ..data
+func swapDataDir(t *testing.T, dir, version string, files map[string]string) {
+	writeTestFiles(t, filepath.Join(dir, ".."+version), files)
+	for name := range files {
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			if err = os.Symlink(filepath.Join("..data", name), link); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(".."+version, tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchDataDirSwap(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "volume")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	swapDataDir(t, dir, "v1", map[string]string{"config.json": `{"name":"a"}`})
+	conf := &watchTestConf{}
+	b := bindTestDir(t, conf, dir)
+	names := make(chan interface{}, 16)
+	if _, err := b.OnChange("Name", func(value, preValue interface{}) { names <- value }); err != nil {
+		t.Fatal(err)
+	}
+	if name := <-names; name != "a" {
+		t.Fatalf("got %v at first, want a", name)
+	}
+	swapDataDir(t, dir, "v2", map[string]string{"config.json": `{"name":"b"}`})
+	select {
+	case name := <-names:
+		if name != "b" {
+			t.Fatalf("got %v after the swap, want b", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the swap of ..data is not applied")
+	}
+	// the file removed by the swap keeps its value and is not a decode failure
+	swapDataDir(t, dir, "v3", map[string]string{"other.json": `{}`})
+	writeTestFiles(t, dir, map[string]string{"rules/c.json": `{"n":3}`})
+	for done := false; !done; {
+		select {
+		case ev := <-b.Events():
+			if ev.Type == EventDecodeFailed {
+				t.Fatalf("got %s of %v for %v", ev.Type, ev.Paths, ev.Err)
+			}
+			done = ev.Type == EventChanged && filepath.Base(ev.Paths[len(ev.Paths)-1]) == "c.json"
+		case <-time.After(5 * time.Second):
+			t.Fatal("the change of c is not applied")
+		}
+	}
+	if conf.Name != "b" {
+		t.Fatalf("got the name %s after the file is removed, want b", conf.Name)
+	}
+}
+
+func TestWatchRejectedKeepsConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"a"}`})
+	errBad := errors.New("bad name")
+	conf := &watchTestConf{}
+	b := bindTestDir(t, conf, dir, WithValidator(func(candidate interface{}) error {
+		if candidate.(*watchTestConf).Name == "bad" {
+			return errBad
+		}
+		return nil
+	}))
+	names := make(chan interface{}, 16)
+	if _, err := b.OnChange("Name", func(value, preValue interface{}) { names <- value }); err != nil {
+		t.Fatal(err)
+	}
+	if name := <-names; name != "a" {
+		t.Fatalf("got %v at first, want a", name)
+	}
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"bad"}`})
+	for rejected := false; !rejected; {
+		select {
+		case ev := <-b.Events():
+			if ev.Type == EventChanged {
+				t.Fatalf("got %s of %v, want the change rejected", ev.Type, ev.Paths)
+			}
+			var validationErr *ValidationError
+			if rejected = ev.Type == EventRejected; rejected && (!errors.As(ev.Err, &validationErr) || !errors.Is(ev.Err, errBad)) {
+				t.Fatalf("got the error %v of %s, want the validation error", ev.Err, ev.Type)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the change is not rejected")
+		}
+	}
+	select {
+	case name := <-names:
+		t.Fatalf("got the rejected name %v", name)
+	default:
+	}
+	if conf.Name != "a" {
+		t.Fatalf("got the name %s, want the last good a", conf.Name)
+	}
+	// the rejected update is not saved
+	err := b.Update(context.Background(), func(target interface{}) error {
+		target.(*watchTestConf).Name = "bad"
+		return nil
+	})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v from Update, want the validation error", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "config.json")); string(data) != `{"name":"bad"}` {
+		t.Fatalf("got the file %s, want it unchanged", data)
+	}
+	// the good change after the rejected one is applied
+	writeTestFiles(t, dir, map[string]string{"config.json": `{"name":"b"}`})
+	select {
+	case name := <-names:
+		if name != "b" {
+			t.Fatalf("got %v, want b", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the good change is not applied")
+	}
+}
@@ -0,0 +1,189 @@
+package objectbind
+
+import (
+	"bufio"
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlFile the loaded node tree and the indent of the yaml file
+type yamlFile struct {
+	node   *yaml.Node
+	indent int
+}
+
+// UnmarshalFile unmarshal the yaml file and keep its node tree
+func (j *yamlCodec) UnmarshalFile(data []byte, v interface{}) (interface{}, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	if node.Kind == 0 {
+		// the empty file
+		return nil, nil
+	}
+	if err := node.Decode(v); err != nil {
+		return nil, err
+	}
+	return &yamlFile{node: &node, indent: yamlIndent(data)}, nil
+}
+
+// MarshalFile marshal v into the loaded node tree, so the comments, the anchors, the order of the keys and
+// the styles of the unchanged values are kept
+func (j *yamlCodec) MarshalFile(v interface{}, state interface{}) ([]byte, error) {
+	f, ok := state.(*yamlFile)
+	if !ok || f.node == nil {
+		return j.Marshal(v)
+	}
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+	// the anchors and the aliases are copied together, so the aliases of the copy do not point to the loaded tree
+	doc := copyYAMLNode(f.node, make(map[*yaml.Node]*yaml.Node))
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		doc.Content[0] = mergeYAMLNode(doc.Content[0], &node)
+	} else {
+		doc = mergeYAMLNode(doc, &node)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(f.indent)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeYAMLNode merge the values of the new node into the old node and return the merged node
+func mergeYAMLNode(old, node *yaml.Node) *yaml.Node {
+	if old.Kind == yaml.AliasNode {
+		if sameYAMLValue(old, node) {
+			return old
+		}
+		return withComments(node, old)
+	}
+	if old.Kind != node.Kind {
+		return withComments(node, old)
+	}
+	switch old.Kind {
+	case yaml.MappingNode:
+		values := make(map[string]*yaml.Node, len(node.Content)/2)
+		var keys []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			values[node.Content[i].Value] = node.Content[i+1]
+			keys = append(keys, node.Content[i])
+		}
+		content := make([]*yaml.Node, 0, len(node.Content))
+		exist := make(map[string]bool, len(old.Content)/2)
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			key := old.Content[i].Value
+			value, ok := values[key]
+			if !ok {
+				continue
+			}
+			exist[key] = true
+			content = append(content, old.Content[i], mergeYAMLNode(old.Content[i+1], value))
+		}
+		for _, key := range keys {
+			value := values[key.Value]
+			// the new empty values are omitted as Marshal does
+			if exist[key.Value] || isEmptyYAMLNode(value) {
+				continue
+			}
+			content = append(content, key, value)
+		}
+		old.Content = content
+	case yaml.SequenceNode:
+		content := make([]*yaml.Node, 0, len(node.Content))
+		for i, value := range node.Content {
+			if i < len(old.Content) {
+				content = append(content, mergeYAMLNode(old.Content[i], value))
+			} else {
+				content = append(content, value)
+			}
+		}
+		old.Content = content
+	case yaml.ScalarNode:
+		if old.Value == node.Value && old.ShortTag() == node.ShortTag() {
+			return old
+		}
+		old.Value = node.Value
+		old.Tag = node.Tag
+		// keep the quoting style of the string unless the new value requires another style
+		if node.ShortTag() != "!!str" || old.ShortTag() != "!!str" ||
+			node.Style != 0 && old.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) == 0 {
+			old.Style = node.Style
+		}
+	default:
+		return withComments(node, old)
+	}
+	return old
+}
+
+// copyYAMLNode deep copy the node tree, the aliases point to the copies of their anchors
+func copyYAMLNode(node *yaml.Node, copies map[*yaml.Node]*yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	if c, ok := copies[node]; ok {
+		return c
+	}
+	c := *node
+	copies[node] = &c
+	c.Alias = copyYAMLNode(node.Alias, copies)
+	if node.Content != nil {
+		c.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			c.Content[i] = copyYAMLNode(child, copies)
+		}
+	}
+	return &c
+}
+
+// sameYAMLValue check if the alias points to the same value as node
+func sameYAMLValue(alias, node *yaml.Node) bool {
+	var a, b interface{}
+	if alias.Decode(&a) != nil || node.Decode(&b) != nil {
+		return false
+	}
+	aData, errA := yaml.Marshal(a)
+	bData, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(aData, bData)
+}
+
+func withComments(node, old *yaml.Node) *yaml.Node {
+	node.HeadComment = old.HeadComment
+	node.LineComment = old.LineComment
+	node.FootComment = old.FootComment
+	return node
+}
+
+func isEmptyYAMLNode(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(node.Content) == 0
+	case yaml.ScalarNode:
+		return node.ShortTag() == "!!null"
+	}
+	return false
+}
+
+// yamlIndent get the indent of the yaml data by its first indented mapping line, the default is 4 as Marshal.
+// The sequences are always indented by the encoder, so their lines are skipped
+func yamlIndent(data []byte) int {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := bytes.TrimLeft([]byte(line), " ")
+		n := len(line) - len(trimmed)
+		if n > 0 && len(trimmed) > 0 && trimmed[0] != '#' && trimmed[0] != '-' {
+			return n
+		}
+	}
+	return 4
+}